@@ -0,0 +1,110 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mergeAnchor gives the result `res` of merging the nodes `a` and `b` the anchor of `a` or, if `a` has none, the one of
+// `b`. Both nodes are recorded as replaced by the result, so that their aliases can be redirected to it. Alias nodes
+// have no anchor, so that the result of merging an alias does not take the anchor of the aliased node.
+func (m *merger) mergeAnchor(a, b, res *yaml.Node) {
+	switch {
+	case len(a.Anchor) > 0:
+		res.Anchor = a.Anchor
+	case len(b.Anchor) > 0:
+		res.Anchor = b.Anchor
+	default:
+		res.Anchor = ""
+
+		return
+	}
+
+	if m.replaced == nil {
+		m.replaced = make(map[*yaml.Node]*yaml.Node)
+	}
+
+	for _, n := range []*yaml.Node{a, b} {
+		if len(n.Anchor) > 0 && n != res {
+			m.replaced[n] = res
+		}
+	}
+}
+
+// replacement gives the node finally replacing the given node in the merged structure.
+func (m *merger) replacement(node *yaml.Node) *yaml.Node {
+	for {
+		next, found := m.replaced[node]
+
+		if !found || next == node {
+			return node
+		}
+
+		node = next
+	}
+}
+
+// fixAnchors makes the anchors of the merged node structure unique, renaming anchors used for different nodes, and
+// redirects all aliases to the nodes replacing their original targets. Aliases whose target is no longer part of the
+// structure are replaced by a copy of the target.
+func (m *merger) fixAnchors(root *yaml.Node) {
+	anchors := make(map[string]*yaml.Node)
+	inTree := make(map[*yaml.Node]bool)
+
+	m.uniqueAnchors(root, anchors, inTree)
+	m.redirectAliases(root, inTree)
+}
+
+// uniqueAnchors renames the anchors of the node structure, that are already used by other nodes.
+func (m *merger) uniqueAnchors(node *yaml.Node, anchors map[string]*yaml.Node, inTree map[*yaml.Node]bool) {
+	if inTree[node] {
+		return
+	}
+
+	inTree[node] = true
+
+	if len(node.Anchor) > 0 {
+		name := node.Anchor
+
+		for i := 1; anchors[name] != nil && anchors[name] != node; i++ {
+			name = node.Anchor + "_" + strconv.Itoa(i)
+		}
+
+		node.Anchor = name
+		anchors[name] = node
+	}
+
+	for _, c := range node.Content {
+		m.uniqueAnchors(c, anchors, inTree)
+	}
+}
+
+// redirectAliases lets the aliases of the node structure refer to the nodes replacing their original targets.
+func (m *merger) redirectAliases(node *yaml.Node, inTree map[*yaml.Node]bool) {
+	for i, c := range node.Content {
+		if c.Kind != yaml.AliasNode || c.Alias == nil {
+			m.redirectAliases(c, inTree)
+
+			continue
+		}
+
+		target := m.replacement(c.Alias)
+
+		if inTree[target] {
+			alias := m.copyNode(c)
+			alias.Alias = target
+			alias.Value = target.Anchor
+			node.Content[i] = alias
+
+			continue
+		}
+
+		inlined := m.copyNode(target)
+		inlined.Anchor = ""
+		node.Content[i] = inlined
+	}
+}
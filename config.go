@@ -6,11 +6,16 @@ package templig
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
 	"text/template"
 
 	"gopkg.in/yaml.v3"
@@ -23,6 +28,9 @@ var (
 
 	// ErrNoConfigPaths indicates that no configuration file paths were provided where at least one is required.
 	ErrNoConfigPaths = errors.New("no configuration paths given")
+
+	// ErrNoConfigSources indicates that no configuration sources were provided where at least one is required.
+	ErrNoConfigSources = errors.New("no configuration sources given")
 )
 
 // Validator is the interface to facility validity checks on configuration types.
@@ -33,8 +41,15 @@ type Validator interface {
 
 // Config is the generic structure holding the configuration information for the specified type.
 type Config[T any] struct {
-	node    *yaml.Node
-	content T
+	node         *yaml.Node
+	content      T
+	secretRE     *regexp.Regexp
+	merger       *merger
+	provenance   map[string]Provenance
+	dependencies []string
+	globs        []dependencyGlob
+	warnings     []string
+	sources      []string
 }
 
 // Get gives a pointer to the deserialized configuration.
@@ -42,19 +57,103 @@ func (c *Config[T]) Get() *T {
 	return &c.content
 }
 
+// Warnings gives the warnings of the loading process, e.g. values replaced by values of a different kind, see
+// [WithKindOverride].
+func (c *Config[T]) Warnings() []string {
+	return slices.Clone(c.warnings)
+}
+
+// Sources gives the names of the sources the configuration was loaded from, in the order they were applied. Besides
+// files and readers, these include e.g. the files of profiles (see [WithProfiles]) or the environment variables.
+func (c *Config[T]) Sources() []string {
+	return slices.Clone(c.sources)
+}
+
 // overlay is called repeatedly and overlays the current intermediate configuration
-// with the content of the given io.Reader.
-func (c *Config[T]) overlay(r io.Reader) error {
-	a, aErr := fromSingle[yaml.Node](r)
+// with the content of the given io.Reader read from the given source. Multiple documents of the content are overlaid
+// in order, if they are chosen by the document selector of the options. Sources without chosen documents are rejected
+// like empty sources. Files included using the `!include` tag are resolved relative to the source.
+func (c *Config[T]) overlay(r io.Reader, src source, format Format, o *options) error {
+	docs, docsErr := fromSingle(r, o.funcs, format)
+
+	if docsErr != nil {
+		return docsErr
+	}
+
+	name := src.name
+	in := includer{o: o, m: c.merger}
+
+	if len(src.path) > 0 {
+		in.stack = append(in.stack, in.includeKey(src.path))
+	}
+
+	selected := 0
+
+	for i, doc := range docs {
+		if !o.selector.selects(doc) {
+			continue
+		}
+
+		selected++
+
+		if err := in.resolve(doc, filepath.Dir(src.path)); err != nil {
+			return fmt.Errorf("could not load %v: %w", name, err)
+		}
+
+		docName := name
+
+		if len(docs) > 1 {
+			docName = fmt.Sprintf("%v (document %d)", name, i+1)
+		}
+
+		if err := c.overlayNode(doc, docName); err != nil {
+			return err
+		}
+	}
+
+	// like empty sources, sources without selected documents are only accepted on top of default values
+	if selected == 0 && len(docs) > 0 && (c.merger == nil || len(c.merger.weakLayers) == 0) {
+		return fmt.Errorf("could not load configuration %v: no document selected: %w", name, io.EOF)
+	}
+
+	return nil
+}
+
+// overlayNode overlays the current intermediate configuration with the given node structure.
+func (c *Config[T]) overlayNode(a *yaml.Node, name string) error {
+	if c.merger == nil {
+		c.merger = new(merger)
+	}
+
+	c.merger.addLayer(name, a)
+
+	if err := c.merger.takeSequenceTags(a); err != nil {
+		return err
+	}
+
+	if err := c.merger.decryptValues(a); err != nil {
+		return err
+	}
+
+	if a.Kind == yaml.DocumentNode && len(a.Content) == 0 {
+		// empty sources are only accepted on top of default values, that they do not change
+		if len(c.merger.weakLayers) == 0 {
+			return fmt.Errorf("could not parse configuration %v: %w", name, io.EOF)
+		}
 
-	if aErr != nil {
-		return aErr
+		return nil
 	}
 
 	if c.node == nil {
-		c.node = a.Get()
+		cleaned, cleanErr := c.merger.dropDeleteMarkers(a)
+
+		if cleanErr != nil {
+			return cleanErr
+		}
+
+		c.node = cleaned
 	} else {
-		merged, mergeErr := MergeYAMLNodes(c.node, a.Get())
+		merged, mergeErr := c.merger.mergeRoot(c.node, a)
 
 		if mergeErr != nil {
 			return mergeErr
@@ -66,23 +165,40 @@ func (c *Config[T]) overlay(r io.Reader) error {
 	return nil
 }
 
-// overlayFile opens a given configuration file and loads it as an intermediate using the overlay function.
-func (c *Config[T]) overlayFile(path string) error {
-	f, err := os.Open(filepath.Clean(path))
+// overlayIndexedNode overlays the current intermediate configuration with the given node structure of a layer, that
+// gives list elements by their index, i.e. environment variables and command line flags.
+func (c *Config[T]) overlayIndexedNode(a *yaml.Node, name string) error {
+	if c.merger == nil {
+		c.merger = new(merger)
+	}
+
+	c.merger.takeIndexTags(a)
+
+	return c.overlayNode(a, name)
+}
+
+// overlaySource reads the given configuration source and loads it as an intermediate using the overlay function.
+// Reading is aborted, when the given context is done.
+func (c *Config[T]) overlaySource(src source, o *options) error {
+	content, err := src.read(o.ctx)
 
 	if err != nil {
-		return fmt.Errorf("could not open overlay file %v: %w", path, err)
+		return fmt.Errorf("could not read overlay %v: %w", src.name, err)
 	}
 
-	defer func() { _ = f.Close() }()
+	format := o.format
 
-	return c.overlay(f)
+	if format == FormatAuto {
+		format = src.format
+	}
+
+	return c.overlay(bytes.NewReader(content), src, format, o)
 }
 
 // fromSingle reads a configuration from the single given io.Reader and
-// runs - if necessary - the contained template functions.
-func fromSingle[T any](r io.Reader) (*Config[T], error) {
-	var config Config[T]
+// runs - if necessary - the contained template functions. The result is
+// parsed according to the given format, giving one node structure per document.
+func fromSingle(r io.Reader, funcs template.FuncMap, format Format) ([]*yaml.Node, error) {
 	fileContent, err := io.ReadAll(r)
 
 	if err != nil {
@@ -93,7 +209,7 @@ func fromSingle[T any](r io.Reader) (*Config[T], error) {
 
 	if tmpl, err = template.
 		New("config").
-		Funcs(templigFunctions()).
+		Funcs(funcs).
 		Parse(string(fileContent)); err != nil {
 		return nil, fmt.Errorf("could not parse template: %w", err)
 	}
@@ -104,11 +220,7 @@ func fromSingle[T any](r io.Reader) (*Config[T], error) {
 		return nil, fmt.Errorf("could not execute template: %w", err)
 	}
 
-	if decodeErr := yaml.NewDecoder(&b).Decode(&config.content); decodeErr != nil {
-		return nil, fmt.Errorf("could not parse configuration: %w", decodeErr)
-	}
-
-	return &config, nil
+	return parseNodes(b.Bytes(), format)
 }
 
 // Validate checks if the configuration is valid if the content fulfills the Validator interface.
@@ -122,54 +234,163 @@ func (c *Config[T]) Validate() error {
 	return nil
 }
 
-// From reads a configuration from the given set of io.Reader.
-func From[T any](readers ...io.Reader) (*Config[T], error) {
-	if len(readers) == 0 {
-		return nil, ErrNoConfigReaders
+// Load reads a configuration from the sources given by the functional options, e.g. [WithFiles] or [WithReaders].
+// The first source is considered the base, all others are loaded on top of that one using the [MergeYAMLNodes]
+// functionality. Default values given using `default` struct tags are used as lowest layer below the first source,
+// lists given in the sources replace the default lists instead of extending them. All other options, like [WithFuncs]
+// or [WithSecretPattern], only affect this loading process and the resulting configuration.
+func Load[T any](opts ...Option) (*Config[T], error) {
+	o := newOptions(opts...)
+
+	if len(o.sources) == 0 && len(o.patches) == 0 && o.env == nil && o.flags == nil {
+		return nil, ErrNoConfigSources
+	}
+
+	var sourcesErr error
+
+	if o.sources, sourcesErr = o.expandSources(); sourcesErr != nil {
+		return nil, sourcesErr
 	}
 
-	var config *Config[T]
+	if o.sources, sourcesErr = o.profileSources(); sourcesErr != nil {
+		return nil, sourcesErr
+	}
+
+	key, keyErr := o.key()
+
+	if keyErr != nil {
+		return nil, keyErr
+	}
+
+	config := &Config[T]{
+		merger: &merger{
+			strategy:       o.sequences,
+			pathStrategies: o.pathSequences,
+			strict:         o.strict,
+			kindOverride:   o.kindOverride,
+			key:            key,
+		},
+	}
 	var decodeErr error
+	var strictErr error
 	var validateErr error
 
-	if len(readers) == 1 {
-		// to optimize the most common case of a single reader, we do not need to
-		// go over the yaml.Node structure first.
-		config, decodeErr = fromSingle[T](readers[0])
-	} else {
-		config = new(Config[T])
+	if node := defaultsNode(reflect.TypeFor[T]()); node != nil {
+		if err := config.overlayNode(node, defaultsLayerName); err != nil {
+			return nil, err
+		}
 
-		for _, v := range readers {
-			if err := config.overlay(v); err != nil {
+		config.merger.weakLayers = map[int]bool{len(config.merger.layers) - 1: true}
+	}
+
+	for _, src := range o.sources {
+		if err := config.overlaySource(src, o); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range o.patches {
+		if err := config.applyPatch(p, o); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.env != nil {
+		if node := envNode(reflect.TypeFor[T](), os.Environ(), o.env); node != nil {
+			if err := config.overlayIndexedNode(node, envLayerName); err != nil {
 				return nil, err
 			}
 		}
+	}
+
+	if o.flags != nil {
+		if node := flagsNode(reflect.TypeFor[T](), o.flags); node != nil {
+			if err := config.overlayIndexedNode(node, flagsLayerName); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if config.node == nil {
+		// none of the layers gave any values
+		config.node = documentNode(containerNode(reflect.TypeFor[T]()))
+		config.merger = new(merger)
+	}
 
-		decodeErr = config.node.Decode(&config.content)
+	decodeErr = wrapError("could not decode configuration: %w", config.node.Decode(&config.content))
 
-		// cleanup
-		config.node = nil
+	if o.strict {
+		strictErr = checkKnownFields(config.node, reflect.TypeFor[T](), "", config.merger.sourceName)
 	}
 
-	if decodeErr == nil {
+	config.provenance = config.merger.buildProvenance(config.node)
+	config.dependencies = o.dependencies()
+	config.globs = o.dependencyGlobs()
+	config.warnings = config.merger.warnings
+	config.sources = config.merger.layers
+
+	// cleanup
+	config.node = nil
+	config.merger = nil
+
+	if decodeErr == nil && strictErr == nil {
+		config.secretRE = o.secretRE
 		validateErr = config.Validate()
 	}
 
-	if resultErr := errors.Join(decodeErr, validateErr); resultErr != nil {
+	if resultErr := errors.Join(decodeErr, strictErr, validateErr); resultErr != nil {
 		return nil, resultErr
 	}
 
 	return config, nil
 }
 
+// FromContext reads a configuration from the given set of io.Reader like [From]. The loading is aborted, when the
+// given context is done. The context is also used by the template functions, see [WithContext].
+func FromContext[T any](ctx context.Context, readers ...io.Reader) (*Config[T], error) {
+	if len(readers) == 0 {
+		return nil, ErrNoConfigReaders
+	}
+
+	return Load[T](WithContext(ctx), WithReaders(readers...))
+}
+
+// From reads a configuration from the given set of io.Reader.
+func From[T any](readers ...io.Reader) (*Config[T], error) {
+	if len(readers) == 0 {
+		return nil, ErrNoConfigReaders
+	}
+
+	return Load[T](WithReaders(readers...))
+}
+
 // To writes a configuration to the given io.Writer.
-func (c *Config[T]) To(w io.Writer) error {
-	return wrapError("could not encode configuration: %w", yaml.NewEncoder(w).Encode(&c.content))
+// The format of the output can be selected using [WithOutputFormat], by default it is YAML.
+func (c *Config[T]) To(w io.Writer, opts ...OutputOption) error {
+	node := yaml.Node{}
+
+	if err := node.Encode(&c.content); err != nil {
+		return fmt.Errorf("could not encode configuration: %w", err)
+	}
+
+	return writeNode(w, &node, newOutputOptions(opts...).format)
+}
+
+// secretPattern gives the regular expression to identify secrets. If none was set using [WithSecretPattern] while
+// loading, [SecretRE] is used.
+func (c *Config[T]) secretPattern() *regexp.Regexp {
+	if c.secretRE != nil {
+		return c.secretRE
+	}
+
+	return SecretRE
 }
 
-// ToSecretsHidden writes the configuration to the given io.Writer and hides secret values using the [SecretRE].
-// Strings are replaced with the number of * corresponding to their length.
-// Substructures containing secrets are replaced with a single '*'.
+// ToSecretsHidden writes the configuration to the given io.Writer and hides secret values. Fields tagged
+// `templig:"secret"` are always hidden, fields tagged `templig:"nosecret"` never. All other values are identified using
+// the [SecretRE] or the pattern given by [WithSecretPattern].
+// Strings are replaced with the number of * corresponding to their length, other masks can be selected using
+// [WithMask]. Substructures containing secrets are replaced with a single '*'.
 // The following example
 //
 //	id: id0
@@ -181,24 +402,28 @@ func (c *Config[T]) To(w io.Writer) error {
 //
 //	id: id0
 //	secrets: *
-func (c *Config[T]) ToSecretsHidden(w io.Writer) error {
+//
+// The format of the output can be selected using [WithOutputFormat], by default it is YAML.
+func (c *Config[T]) ToSecretsHidden(w io.Writer, opts ...OutputOption) error {
 	var writeErr error
 	node := yaml.Node{}
 
 	encodeErr := node.Encode(c.content)
 
 	if encodeErr == nil {
-		HideSecrets(&node, true)
-		writeErr = yaml.NewEncoder(w).Encode(node)
+		o := newOutputOptions(opts...)
+		hideTypedSecrets(&node, reflect.TypeFor[T](), true, c.secretPattern(), o.mask)
+		maskSecretValues(&node, reflect.ValueOf(c.content), true, o.mask)
+		writeErr = writeNode(w, &node, o.format)
 	}
 
 	return errors.Join(encodeErr, writeErr)
 }
 
 // ToSecretsHiddenStructured writes the configuration to the given io.Writer
-// and hides secret values using the [SecretRE].
-// Strings are replaced with the number of * corresponding to their length.
-// Substructures containing secrets are replaced with a corresponding structure of '*'.
+// and hides secret values like [Config.ToSecretsHidden].
+// Strings are replaced with the number of * corresponding to their length, other masks can be selected using
+// [WithMask]. Substructures containing secrets are replaced with a corresponding structure of '*'.
 // The following example
 //
 //	id: id0
@@ -212,15 +437,19 @@ func (c *Config[T]) ToSecretsHidden(w io.Writer) error {
 //	secrets:
 //	  - *******
 //	  - *******
-func (c *Config[T]) ToSecretsHiddenStructured(w io.Writer) error {
+//
+// The format of the output can be selected using [WithOutputFormat], by default it is YAML.
+func (c *Config[T]) ToSecretsHiddenStructured(w io.Writer, opts ...OutputOption) error {
 	var writeErr error
 	node := yaml.Node{}
 
 	encodeErr := node.Encode(c.content)
 
 	if encodeErr == nil {
-		HideSecrets(&node, false)
-		writeErr = yaml.NewEncoder(w).Encode(node)
+		o := newOutputOptions(opts...)
+		hideTypedSecrets(&node, reflect.TypeFor[T](), false, c.secretPattern(), o.mask)
+		maskSecretValues(&node, reflect.ValueOf(c.content), false, o.mask)
+		writeErr = writeNode(w, &node, o.format)
 	}
 
 	return errors.Join(encodeErr, writeErr)
@@ -233,46 +462,27 @@ func FromFile[T any](paths ...string) (*Config[T], error) {
 		return nil, ErrNoConfigPaths
 	}
 
-	config := new(Config[T])
-	var decodeErr error
-	var validateErr error
-
-	if len(paths) == 1 {
-		// to optimize the most common case of a single file, we do not need to
-		// go over the yaml.Node structure first.
-		f, err := os.Open(paths[0])
-
-		if err != nil {
-			return nil, fmt.Errorf("could not open %s: %w", paths[0], err)
-		}
-
-		defer func() { _ = f.Close() }()
-
-		config, decodeErr = fromSingle[T](f)
-	} else {
-		for _, addOn := range paths[0:] {
-			aErr := config.overlayFile(addOn)
-
-			if aErr != nil {
-				return nil, aErr
-			}
-		}
-
-		decodeErr = config.node.Decode(&config.content)
+	return Load[T](WithFiles(paths...))
+}
 
-		// cleanup
-		config.node = nil
+// FromFileContext loads a series of configuration files like [FromFile]. The loading is aborted, when the given
+// context is done. The context is also used by the template functions, see [WithContext].
+func FromFileContext[T any](ctx context.Context, paths ...string) (*Config[T], error) {
+	if len(paths) == 0 {
+		return nil, ErrNoConfigPaths
 	}
 
-	if decodeErr == nil {
-		validateErr = config.Validate()
-	}
+	return Load[T](WithContext(ctx), WithFiles(paths...))
+}
 
-	if resultErr := errors.Join(decodeErr, validateErr); resultErr != nil {
-		return nil, resultErr
+// FromFS loads a series of configuration files from the given file system, like [FromFile] does from the operating
+// system. Files read using the `read` template function are also resolved against the given file system.
+func FromFS[T any](fsys fs.FS, paths ...string) (*Config[T], error) {
+	if len(paths) == 0 {
+		return nil, ErrNoConfigPaths
 	}
 
-	return config, nil
+	return Load[T](WithFS(fsys), WithFiles(paths...))
 }
 
 // FromFiles loads a series of configuration files. The first file is considered the base, all others are
@@ -284,7 +494,8 @@ func FromFiles[T any](paths []string) (*Config[T], error) {
 }
 
 // ToFile saves a configuration to a file with the given name, replacing it in case.
-func (c *Config[T]) ToFile(path string) error {
+// The format is detected using the file extension, unless it is given using [WithOutputFormat].
+func (c *Config[T]) ToFile(path string, opts ...OutputOption) error {
 	f, err := os.Create(filepath.Clean(path))
 
 	if err != nil {
@@ -293,5 +504,5 @@ func (c *Config[T]) ToFile(path string) error {
 
 	defer func() { _ = f.Close() }()
 
-	return c.To(f)
+	return c.To(f, append([]OutputOption{WithOutputFormat(formatOf(path))}, opts...)...)
 }
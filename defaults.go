@@ -0,0 +1,34 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultsLayerName is the source name of the default values given by struct tags.
+const defaultsLayerName = "defaults"
+
+// defaultsNode creates the node structure of the default values given by the `default` tags of the configuration type
+// t and its substructures. Values of lists are given comma-separated, durations like `5s`. If no default is given,
+// nil is returned.
+func defaultsNode(t reflect.Type) *yaml.Node {
+	root := containerNode(t)
+	found := false
+
+	walkFields(t, "", func(path string, field fieldInfo) {
+		if value, ok := field.tag.Lookup("default"); ok && setTypedPath(root, t, strings.Split(path, "."), value) {
+			found = true
+		}
+	})
+
+	if !found {
+		return nil
+	}
+
+	return documentNode(root)
+}
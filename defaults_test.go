@@ -0,0 +1,139 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/templig"
+)
+
+type TestDefaultsDatabase struct {
+	URL     string        `yaml:"url"     default:"postgres://localhost"`
+	Port    int           `yaml:"port"    default:"5432"`
+	Timeout time.Duration `yaml:"timeout" default:"5s"`
+}
+
+type TestDefaultsConfig struct {
+	Name     string               `yaml:"name"     default:"service"`
+	Debug    bool                 `yaml:"debug"`
+	Origins  []string             `yaml:"origins"  default:"a.com, b.com"`
+	Database TestDefaultsDatabase `yaml:"database"`
+}
+
+func TestDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected TestDefaultsConfig
+	}{
+		{
+			name:    "empty",
+			content: "",
+			expected: TestDefaultsConfig{
+				Name:    "service",
+				Origins: []string{"a.com", "b.com"},
+				Database: TestDefaultsDatabase{
+					URL:     "postgres://localhost",
+					Port:    5432,
+					Timeout: 5 * time.Second,
+				},
+			},
+		},
+		{
+			name:    "comments only",
+			content: "# nothing configured\n",
+			expected: TestDefaultsConfig{
+				Name:    "service",
+				Origins: []string{"a.com", "b.com"},
+				Database: TestDefaultsDatabase{
+					URL:     "postgres://localhost",
+					Port:    5432,
+					Timeout: 5 * time.Second,
+				},
+			},
+		},
+		{
+			name:    "overridden",
+			content: "debug: true\norigins:\n  - c.com\ndatabase:\n  port: 5433\n",
+			expected: TestDefaultsConfig{
+				Name:    "service",
+				Debug:   true,
+				Origins: []string{"c.com"},
+				Database: TestDefaultsDatabase{
+					URL:     "postgres://localhost",
+					Port:    5433,
+					Timeout: 5 * time.Second,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, loadErr := templig.From[TestDefaultsConfig](strings.NewReader(test.content))
+
+			if loadErr != nil {
+				t.Fatalf("could not load configuration with defaults: %v", loadErr)
+			}
+
+			got := c.Get()
+
+			if got.Name != test.expected.Name ||
+				got.Debug != test.expected.Debug ||
+				!slices.Equal(got.Origins, test.expected.Origins) ||
+				got.Database != test.expected.Database {
+				t.Errorf("expected %+v but got %+v", test.expected, *got)
+			}
+		})
+	}
+}
+
+func TestDefaultsProvenance(t *testing.T) {
+	c, loadErr := templig.From[TestDefaultsConfig](strings.NewReader("database:\n  port: 5433\n"))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration with defaults: %v", loadErr)
+	}
+
+	if p, _ := c.Explain("database.url"); p.Source != "defaults" {
+		t.Errorf("expected default value to originate from the defaults, got %v", p)
+	}
+
+	if p, _ := c.Explain("database.port"); p.Source != "reader 0" || len(p.Overridden) != 1 ||
+		p.Overridden[0].Source != "defaults" {
+		t.Errorf("expected value overriding the default, got %v", p)
+	}
+}
+
+func TestDefaultsWrongType(t *testing.T) {
+	type wrongDefault struct {
+		Port int `yaml:"port" default:"notANumber"`
+	}
+
+	if _, loadErr := templig.From[wrongDefault](strings.NewReader("")); loadErr == nil {
+		t.Errorf("expected error for default value of wrong type")
+	}
+}
+
+type TestRecursiveConfig struct {
+	Name     string                `yaml:"name"     default:"root"`
+	Next     *TestRecursiveConfig  `yaml:"next"`
+	Children []TestRecursiveConfig `yaml:"children"`
+}
+
+func TestDefaultsRecursive(t *testing.T) {
+	c, loadErr := templig.From[TestRecursiveConfig](strings.NewReader("next:\n  name: second\n"))
+
+	if loadErr != nil {
+		t.Fatalf("could not load recursive configuration: %v", loadErr)
+	}
+
+	if c.Get().Name != "root" || c.Get().Next == nil || c.Get().Next.Name != "second" {
+		t.Errorf("unexpected configuration: %+v", c.Get())
+	}
+}
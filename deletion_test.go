@@ -0,0 +1,120 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AlphaOne1/templig"
+)
+
+func TestDeleteMarkers(t *testing.T) {
+	base := `
+id: 9
+name: Base
+conn:
+  url: https://base
+  passes:
+    - pass0
+`
+
+	tests := []struct {
+		name     string
+		overlay  string
+		expected string
+	}{
+		{
+			name:     "delete scalar",
+			overlay:  "name: !delete\n",
+			expected: "id: 9\nname: \"\"\nconn:\n    url: https://base\n    passes:\n        - pass0\n",
+		},
+		{
+			name:     "unset subtree",
+			overlay:  "conn: !unset ~\n",
+			expected: "id: 9\nname: Base\n",
+		},
+		{
+			name:     "delete nested",
+			overlay:  "conn:\n  passes: !delete\n",
+			expected: "id: 9\nname: Base\nconn:\n    url: https://base\n    passes: []\n",
+		},
+		{
+			name:     "delete missing",
+			overlay:  "unknown: !delete\nconn:\n  other: !delete\n",
+			expected: "id: 9\nname: Base\nconn:\n    url: https://base\n    passes:\n        - pass0\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, loadErr := templig.From[TestConfig](strings.NewReader(base), strings.NewReader(test.overlay))
+
+			if loadErr != nil {
+				t.Fatalf("could not load configuration: %v", loadErr)
+			}
+
+			buf := bytes.Buffer{}
+
+			if err := c.To(&buf); err != nil {
+				t.Fatalf("could not write configuration: %v", err)
+			}
+
+			if buf.String() != test.expected {
+				t.Errorf("expected\n%v\nbut got\n%v", test.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestDeleteMarkersStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		readers []string
+		wantErr bool
+	}{
+		{
+			name:    "existing target",
+			readers: []string{"id: 9\nname: Base\n", "name: !delete\n"},
+			wantErr: false,
+		},
+		{
+			name:    "missing target",
+			readers: []string{"id: 9\n", "name: !delete\n"},
+			wantErr: true,
+		},
+		{
+			name:    "missing nested target",
+			readers: []string{"id: 9\n", "conn:\n  url: !delete\n"},
+			wantErr: true,
+		},
+		{
+			name:    "marker in base",
+			readers: []string{"id: 9\nname: !unset\n"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			readers := make([]templig.Option, 0, len(test.readers))
+
+			for _, r := range test.readers {
+				readers = append(readers, templig.WithReaders(strings.NewReader(r)))
+			}
+
+			_, loadErr := templig.Load[TestConfig](append(readers, templig.WithStrict())...)
+
+			if test.wantErr && !errors.Is(loadErr, templig.ErrDeleteTargetMissing) {
+				t.Errorf("expected error %v but got %v", templig.ErrDeleteTargetMissing, loadErr)
+			}
+
+			if !test.wantErr && loadErr != nil {
+				t.Errorf("did not expect error but got %v", loadErr)
+			}
+		})
+	}
+}
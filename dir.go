@@ -0,0 +1,143 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// backupSuffixes are the suffixes of editor backup and temporary files, that are ignored in directories and globs.
+var backupSuffixes = []string{"~", ".bak", ".orig", ".swp", ".swo", ".tmp"} //nolint:gochecknoglobals
+
+// configExtensions are the extensions of the files loaded from directories.
+var configExtensions = []string{".yaml", ".yml", ".json", ".toml", ".env"} //nolint:gochecknoglobals
+
+// WithDir adds the configuration files of the given directory, e.g. `config.d`, to the configuration sources. The files
+// with the extensions `.yaml`, `.yml`, `.json`, `.toml` and `.env` are overlaid in lexical order, hidden files and
+// editor backup files are ignored. The directory is read when the configuration is loaded, so a missing directory
+// just gives no files.
+func WithDir(dir string) Option {
+	return func(o *options) {
+		o.sources = append(o.sources, source{
+			name:       dir,
+			glob:       filepath.Join(dir, "*"),
+			configOnly: true,
+		})
+	}
+}
+
+// WithGlob adds the files matching the given pattern, e.g. `config.d/*.yaml`, to the configuration sources. The
+// files are overlaid in lexical order, hidden files and editor backup files are ignored. The syntax of the pattern is
+// the one of filepath.Match, or of path.Match, if a file system is given using [WithFS].
+func WithGlob(pattern string) Option {
+	return func(o *options) {
+		o.sources = append(o.sources, source{
+			name: pattern,
+			glob: pattern,
+		})
+	}
+}
+
+// dependencyGlob is a pattern of the files a loading process depends on, e.g. the files of a configuration directory.
+// Files matching the pattern that are created later cause a reload by [Watch].
+type dependencyGlob struct {
+	pattern    string
+	configOnly bool
+}
+
+// matches checks if the file with the given name matches the pattern and is not ignored.
+func (g dependencyGlob) matches(name string) bool {
+	matched, err := filepath.Match(g.pattern, name)
+
+	return err == nil && matched && acceptedFile(name, g.configOnly)
+}
+
+// FromDir loads the given base configuration file and overlays the configuration files of the given directory on top
+// of it, see [WithDir].
+func FromDir[T any](base string, dir string) (*Config[T], error) {
+	return Load[T](WithFiles(base), WithDir(dir))
+}
+
+// ignoredFile checks if the file with the given name is a hidden or an editor backup file.
+func ignoredFile(name string) bool {
+	base := filepath.Base(name)
+
+	return strings.HasPrefix(base, ".") ||
+		strings.HasPrefix(base, "#") ||
+		slices.ContainsFunc(backupSuffixes, func(s string) bool { return strings.HasSuffix(base, s) })
+}
+
+// acceptedFile checks if the file with the given name is loaded from directories and globs. If only configuration files
+// are accepted, the file needs to have one of the configuration extensions.
+func acceptedFile(name string, configOnly bool) bool {
+	return !ignoredFile(name) &&
+		(!configOnly || slices.Contains(configExtensions, strings.ToLower(filepath.Ext(name))))
+}
+
+// profileVariant checks if the file with the given name is the file of one of the given profiles for one of the
+// given files, e.g. `a.prod.yaml` for `a.yaml` and the profile `prod`.
+func profileVariant(name string, files []string, profiles []string) bool {
+	return slices.ContainsFunc(files, func(f string) bool {
+		return slices.ContainsFunc(profiles, func(p string) bool { return profilePath(f, p) == name })
+	})
+}
+
+// expandSources replaces the directory and glob sources by the sources of the files they match. The patterns are
+// recorded, so that files created later can be detected. Files of active profiles, e.g. `a.prod.yaml` next to
+// `a.yaml`, are skipped, as they are added after their configuration file.
+func (o *options) expandSources() ([]source, error) {
+	result := make([]source, 0, len(o.sources))
+	profiles := o.activeProfiles()
+
+	for _, src := range o.sources {
+		if len(src.glob) == 0 {
+			result = append(result, src)
+
+			continue
+		}
+
+		var matches []string
+		var err error
+
+		if o.fsys != nil {
+			matches, err = fs.Glob(o.fsys, path.Clean(filepath.ToSlash(src.glob)))
+		} else {
+			matches, err = filepath.Glob(src.glob)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("could not expand %v: %w", src.name, err)
+		}
+
+		o.globs = append(o.globs, dependencyGlob{pattern: src.glob, configOnly: src.configOnly})
+		slices.Sort(matches)
+
+		files := make([]string, 0, len(matches))
+
+		for _, m := range matches {
+			if !acceptedFile(m, src.configOnly) {
+				continue
+			}
+
+			if info, statErr := o.stat(m); statErr != nil || info.IsDir() {
+				continue
+			}
+
+			files = append(files, m)
+		}
+
+		for _, f := range files {
+			if !profileVariant(f, files, profiles) {
+				result = append(result, o.fileSource(f))
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,186 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/AlphaOne1/templig"
+)
+
+func TestFromDir(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, d := range []string{"config.d", filepath.Join("config.d", "sub")} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0o700); err != nil {
+			t.Fatalf("could not create directory %v: %v", d, err)
+		}
+	}
+
+	writeFile(t, filepath.Join(dir, "config.yaml"), "id: 1\nname: base\nconn:\n  url: https://base\n")
+	writeFile(t, filepath.Join(dir, "config.d", "20-name.yaml"), "name: fragment\n")
+	writeFile(t, filepath.Join(dir, "config.d", "10-id.json"), `{"id": 2}`)
+	writeFile(t, filepath.Join(dir, "config.d", "30-passes.toml"), "[conn]\npasses = [\"pass0\"]\n")
+	writeFile(t, filepath.Join(dir, "config.d", "20-name.yaml~"), "name: backup\n")
+	writeFile(t, filepath.Join(dir, "config.d", ".20-name.yaml.swp"), "name: swap\n")
+	writeFile(t, filepath.Join(dir, "config.d", "README.md"), "not a configuration\n")
+	writeFile(t, filepath.Join(dir, "config.d", "sub", "40-sub.yaml"), "name: sub\n")
+
+	c, loadErr := templig.FromDir[TestConfig](filepath.Join(dir, "config.yaml"), filepath.Join(dir, "config.d"))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration from directory: %v", loadErr)
+	}
+
+	if c.Get().ID != 2 || c.Get().Name != "fragment" ||
+		c.Get().Conn == nil || !slices.Equal(c.Get().Conn.Passes, []string{"pass0"}) {
+		t.Errorf("unexpected configuration: %+v", c.Get())
+	}
+
+	expected := []string{
+		filepath.Join(dir, "config.yaml"),
+		filepath.Join(dir, "config.d", "10-id.json"),
+		filepath.Join(dir, "config.d", "20-name.yaml"),
+		filepath.Join(dir, "config.d", "30-passes.toml"),
+	}
+
+	if !slices.Equal(c.Sources(), expected) {
+		t.Errorf("expected sources %v but got %v", expected, c.Sources())
+	}
+}
+
+func TestFromDirMissing(t *testing.T) {
+	c, loadErr := templig.FromDir[TestConfig]("testData/test_config_0.yaml", "testData/missing.d")
+
+	if loadErr != nil {
+		t.Fatalf("missing directory should give no overlays: %v", loadErr)
+	}
+
+	if c.Get().ID != 9 {
+		t.Errorf("unexpected configuration: %+v", c.Get())
+	}
+}
+
+func TestLoadGlob(t *testing.T) {
+	fsys := fstest.MapFS{
+		"conf/b.yaml":     {Data: []byte("name: b\n")},
+		"conf/a.yaml":     {Data: []byte("id: 1\nname: a\n")},
+		"conf/a.yaml.bak": {Data: []byte("name: backup\n")},
+		"conf/c.json":     {Data: []byte(`{"name": "c"}`)},
+	}
+
+	c, loadErr := templig.Load[TestConfig](templig.WithFS(fsys), templig.WithGlob("conf/*.yaml*"))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration from glob: %v", loadErr)
+	}
+
+	if c.Get().ID != 1 || c.Get().Name != "b" {
+		t.Errorf("unexpected configuration: %+v", c.Get())
+	}
+
+	if expected := []string{"conf/a.yaml", "conf/b.yaml"}; !slices.Equal(c.Sources(), expected) {
+		t.Errorf("expected sources %v but got %v", expected, c.Sources())
+	}
+}
+
+func TestWatchDir(t *testing.T) {
+	dir := t.TempDir()
+	configDir := filepath.Join(dir, "config.d")
+
+	if err := os.Mkdir(configDir, 0o700); err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "config.yaml"), "id: 1\nname: base\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, watchErr := templig.WatchLoad[TestConfig](ctx,
+		templig.WithFiles(filepath.Join(dir, "config.yaml")),
+		templig.WithDir(configDir))
+
+	if watchErr != nil {
+		t.Fatalf("could not watch configuration: %v", watchErr)
+	}
+
+	changes := make(chan string, 10)
+
+	w.OnChange(func(_, newConfig *TestConfig) {
+		changes <- newConfig.Name
+	})
+
+	// fragment added after loading
+	writeFile(t, filepath.Join(configDir, "20-name.yaml"), "name: fragment\n")
+
+	select {
+	case name := <-changes:
+		if name != "fragment" {
+			t.Errorf("expected name fragment but got %v", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change notification received")
+	}
+}
+
+func TestLoadDirProfiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.yaml":           {Data: []byte("id: 1\nname: base\n")},
+		"config.d/a.yaml":       {Data: []byte("conn:\n  passes: [x]\n")},
+		"config.d/a.prod.yaml":  {Data: []byte("conn:\n  passes: [p]\n")},
+		"config.d/c.extra.yaml": {Data: []byte("conn:\n  url: https://extra\n")},
+		"config.d/c.yaml":       {Data: []byte("name: c\n")},
+	}
+
+	tests := []struct {
+		name        string
+		profiles    []string
+		wantPasses  []string
+		wantSources []string
+	}{
+		{
+			name:       "no profiles",
+			wantPasses: []string{"p", "x"},
+			wantSources: []string{"config.yaml",
+				"config.d/a.prod.yaml", "config.d/a.yaml", "config.d/c.extra.yaml", "config.d/c.yaml"},
+		},
+		{
+			name:       "active profile",
+			profiles:   []string{"prod"},
+			wantPasses: []string{"x", "p"},
+			wantSources: []string{"config.yaml",
+				"config.d/a.yaml", "config.d/a.prod.yaml", "config.d/c.extra.yaml", "config.d/c.yaml"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, loadErr := templig.Load[TestConfig](
+				templig.WithFS(fsys),
+				templig.WithFiles("config.yaml"),
+				templig.WithDir("config.d"),
+				templig.WithProfiles(test.profiles...))
+
+			if loadErr != nil {
+				t.Fatalf("could not load configuration: %v", loadErr)
+			}
+
+			if c.Get().Name != "c" || c.Get().Conn == nil || c.Get().Conn.URL != "https://extra" ||
+				!slices.Equal(c.Get().Conn.Passes, test.wantPasses) {
+				t.Errorf("unexpected configuration: %+v", c.Get())
+			}
+
+			if !slices.Equal(c.Sources(), test.wantSources) {
+				t.Errorf("expected sources %v but got %v", test.wantSources, c.Sources())
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// documentSelector chooses the documents of multi-document sources using the value of a top-level key.
+type documentSelector struct {
+	key    string
+	values []string
+}
+
+// WithDocumentSelector chooses the documents of YAML sources using the value of the given top-level key. Documents
+// without the key are always used, documents with the key only if its value is one of the given values. The key itself
+// is removed from the documents. E.g. with `WithDocumentSelector("profile", "prod")`, the following file
+//
+//	name: service
+//	---
+//	profile: dev
+//	debug: true
+//	---
+//	profile: prod
+//	replicas: 3
+//
+// gives the name and the replicas, but not the debug setting.
+func WithDocumentSelector(key string, values ...string) Option {
+	return func(o *options) {
+		o.selector = &documentSelector{key: key, values: values}
+	}
+}
+
+// selects checks if the given document is chosen by the selector, removing the selecting key in case. Without selector,
+// all documents are chosen.
+func (s *documentSelector) selects(doc *yaml.Node) bool {
+	if s == nil || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return true
+	}
+
+	mapping := doc.Content[0]
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != s.key {
+			continue
+		}
+
+		if !slices.Contains(s.values, mapping.Content[i+1].Value) {
+			return false
+		}
+
+		mapping.Content = slices.Delete(mapping.Content, i, i+2)
+
+		return true
+	}
+
+	return true
+}
@@ -0,0 +1,120 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/AlphaOne1/templig"
+)
+
+const testMultiDocument = `id: 1
+name: base
+---
+# empty documents are ignored
+---
+profile: dev
+name: development
+---
+profile: prod
+name: production
+conn:
+  url: https://prod
+`
+
+func TestMultiDocument(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []templig.Option
+		wantName string
+		wantURL  string
+	}{
+		{
+			name:     "all documents",
+			wantName: "production",
+			wantURL:  "https://prod",
+		},
+		{
+			name:     "selected dev",
+			opts:     []templig.Option{templig.WithDocumentSelector("profile", "dev")},
+			wantName: "development",
+		},
+		{
+			name:     "selected prod",
+			opts:     []templig.Option{templig.WithDocumentSelector("profile", "prod"), templig.WithStrict()},
+			wantName: "production",
+			wantURL:  "https://prod",
+		},
+		{
+			name:     "selected none",
+			opts:     []templig.Option{templig.WithDocumentSelector("profile", "test")},
+			wantName: "base",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := append([]templig.Option{templig.WithReaders(strings.NewReader(testMultiDocument))}, test.opts...)
+
+			c, loadErr := templig.Load[TestConfig](opts...)
+
+			if loadErr != nil {
+				t.Fatalf("could not load multi-document configuration: %v", loadErr)
+			}
+
+			if c.Get().ID != 1 || c.Get().Name != test.wantName {
+				t.Errorf("unexpected configuration: %+v", c.Get())
+			}
+
+			url := ""
+
+			if c.Get().Conn != nil {
+				url = c.Get().Conn.URL
+			}
+
+			if url != test.wantURL {
+				t.Errorf("expected url %v but got %v", test.wantURL, url)
+			}
+		})
+	}
+}
+
+func TestMultiDocumentProvenance(t *testing.T) {
+	c, loadErr := templig.From[TestConfig](strings.NewReader(testMultiDocument))
+
+	if loadErr != nil {
+		t.Fatalf("could not load multi-document configuration: %v", loadErr)
+	}
+
+	p, _ := c.Explain("name")
+
+	if p.Source != "reader 0 (document 3)" || p.Line != 10 || len(p.Overridden) != 2 {
+		t.Errorf("unexpected provenance: %v", p)
+	}
+}
+
+func TestMultiDocumentNoneSelected(t *testing.T) {
+	_, loadErr := templig.Load[TestConfig](
+		templig.WithReaders(strings.NewReader("profile: dev\nname: development\n")),
+		templig.WithDocumentSelector("profile", "prod"))
+
+	if !errors.Is(loadErr, io.EOF) {
+		t.Errorf("expected error %v but got %v", io.EOF, loadErr)
+	}
+
+	c, defaultsErr := templig.Load[TestDefaultsConfig](
+		templig.WithReaders(strings.NewReader("profile: dev\nname: development\n")),
+		templig.WithDocumentSelector("profile", "prod"))
+
+	if defaultsErr != nil {
+		t.Fatalf("deselected documents should be accepted on top of defaults: %v", defaultsErr)
+	}
+
+	if c.Get().Name != "service" {
+		t.Errorf("expected default name service but got %v", c.Get().Name)
+	}
+}
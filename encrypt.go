@@ -0,0 +1,484 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrInvalidKey is returned if an encryption key does not have the size of an AES-256 key.
+	ErrInvalidKey = errors.New("invalid encryption key")
+
+	// ErrMissingKey is returned if a configuration contains encrypted values, but no key was given to decrypt them.
+	ErrMissingKey = errors.New("missing decryption key")
+
+	// ErrDecryptionFailed is returned if an encrypted value cannot be decrypted, e.g. because of a wrong key.
+	ErrDecryptionFailed = errors.New("decryption failed")
+
+	// ErrValueNotFound is returned if a value to encrypt does not exist.
+	ErrValueNotFound = errors.New("value not found")
+)
+
+// KeySize is the size of the keys used to encrypt and decrypt values.
+const KeySize = 32
+
+// encryptedTag is the tag of encrypted scalars, as alternative to the `ENC[...]` envelope.
+const encryptedTag = "!encrypted"
+
+// encryptedPrefix and encryptedSuffix enclose the encrypted value in the envelope form.
+const (
+	encryptedPrefix = "ENC["
+	encryptedSuffix = "]"
+)
+
+// WithDecryptionKey sets the key to decrypt encrypted values of the configuration sources, see [EncryptValue].
+func WithDecryptionKey(key []byte) Option {
+	return func(o *options) {
+		o.decryptionKey = key
+		o.keyFile = ""
+	}
+}
+
+// WithDecryptionKeyFile sets the file containing the base64 encoded key to decrypt encrypted values of the
+// configuration sources, see [EncryptValue]. The file is read when the configuration is loaded.
+func WithDecryptionKeyFile(path string) Option {
+	return func(o *options) {
+		o.decryptionKey = nil
+		o.keyFile = path
+	}
+}
+
+// GenerateKey generates a new random key to encrypt values. Stored base64 encoded in a file, it can be used with
+// [WithDecryptionKeyFile].
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("could not generate key: %w", err)
+	}
+
+	return key, nil
+}
+
+// readKeyFile reads the base64 encoded key of the given file.
+func readKeyFile(path string) ([]byte, error) {
+	content, readErr := os.ReadFile(filepath.Clean(path))
+
+	if readErr != nil {
+		return nil, fmt.Errorf("could not read key file: %w", readErr)
+	}
+
+	key, decodeErr := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+
+	if decodeErr != nil {
+		return nil, fmt.Errorf("%w in %v: %w", ErrInvalidKey, path, decodeErr)
+	}
+
+	return key, nil
+}
+
+// key gives the key to decrypt values, if one was given.
+func (o *options) key() ([]byte, error) {
+	if len(o.keyFile) > 0 {
+		return readKeyFile(o.keyFile)
+	}
+
+	return o.decryptionKey, nil
+}
+
+// newAEAD creates the AES-GCM cipher of the given key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("%w: size %v instead of %v", ErrInvalidKey, len(key), KeySize)
+	}
+
+	block, blockErr := aes.NewCipher(key)
+
+	if blockErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, blockErr)
+	}
+
+	return cipher.NewGCM(block) //nolint:wrapcheck
+}
+
+// encrypt encrypts the given plain text and gives it in the envelope form.
+func encrypt(key []byte, plain []byte) (string, error) {
+	aead, aeadErr := newAEAD(key)
+
+	if aeadErr != nil {
+		return "", aeadErr
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("could not generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, plain, nil)
+
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed) + encryptedSuffix, nil
+}
+
+// decrypt decrypts the given value given in the envelope form or just base64 encoded.
+func decrypt(key []byte, value string) ([]byte, error) {
+	aead, aeadErr := newAEAD(key)
+
+	if aeadErr != nil {
+		return nil, aeadErr
+	}
+
+	value = strings.TrimSpace(value)
+	value = strings.TrimSuffix(strings.TrimPrefix(value, encryptedPrefix), encryptedSuffix)
+	data, decodeErr := base64.StdEncoding.DecodeString(value)
+
+	if decodeErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecryptionFailed, decodeErr)
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: value too short", ErrDecryptionFailed)
+	}
+
+	plain, openErr := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+
+	if openErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecryptionFailed, openErr)
+	}
+
+	return plain, nil
+}
+
+// EncryptValue encrypts the given string with the given key. The result has the form `ENC[...]` and can be used as
+// value in configuration files, that are then loaded using [WithDecryptionKey] or [WithDecryptionKeyFile]. Instead of
+// the envelope, the encrypted value can also be tagged, e.g. `pass: !encrypted ...`.
+func EncryptValue(key []byte, value string) (string, error) {
+	return encryptNode(key, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+}
+
+// encryptNode encrypts the given node structure. The node is encrypted in its YAML form, so its type is retained.
+func encryptNode(key []byte, node *yaml.Node) (string, error) {
+	plain, marshalErr := yaml.Marshal(node)
+
+	if marshalErr != nil {
+		return "", fmt.Errorf("could not encode value: %w", marshalErr)
+	}
+
+	return encrypt(key, plain)
+}
+
+// isEncrypted checks if the given node is an encrypted value, either tagged or in the envelope form.
+func isEncrypted(node *yaml.Node) bool {
+	return isTaggedEncrypted(node) || isEnvelope(node)
+}
+
+// isTaggedEncrypted checks if the given node is a value tagged as encrypted.
+func isTaggedEncrypted(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == encryptedTag
+}
+
+// isEnvelope checks if the given node is an unquoted value in the envelope form `ENC[...]`. Quoted values are plain
+// strings, e.g. patterns like `'ENC[A-Z]'`.
+func isEnvelope(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode &&
+		node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) == 0 &&
+		strings.HasPrefix(node.Value, encryptedPrefix) && strings.HasSuffix(node.Value, encryptedSuffix)
+}
+
+// EncryptYAMLNode encrypts the values at the given dotted paths of the node structure in place, e.g. `database.pass`.
+// Elements of sequences are given by their index. Values containing mappings or sequences are encrypted value by
+// value, so their keys stay visible. Without paths, all values are encrypted. Values already encrypted are kept.
+func EncryptYAMLNode(node *yaml.Node, key []byte, paths ...string) error {
+	if len(paths) == 0 {
+		return encryptAll(node, key)
+	}
+
+	for _, p := range paths {
+		if err := encryptPath(node, key, p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encryptPath encrypts the value at the given dotted path of the node structure in place.
+func encryptPath(node *yaml.Node, key []byte, path string) error {
+	target := node
+
+	if target.Kind == yaml.DocumentNode && len(target.Content) == 1 {
+		target = target.Content[0]
+	}
+
+	var parents []*yaml.Node
+
+	for _, element := range strings.Split(path, ".") {
+		target = resolveAlias(target)
+		parents = append(parents, target)
+
+		switch target.Kind { //nolint:exhaustive
+		case yaml.MappingNode:
+			if index := mappingIndex(target, element); index >= 0 {
+				target = target.Content[index+1]
+
+				continue
+			}
+		case yaml.SequenceNode:
+			if index, err := strconv.Atoi(element); err == nil && index >= 0 && index < len(target.Content) {
+				target = target.Content[index]
+
+				continue
+			}
+		}
+
+		return fmt.Errorf("%w: %v", ErrValueNotFound, path)
+	}
+
+	if err := encryptAll(target, key); err != nil {
+		return fmt.Errorf("could not encrypt %v: %w", path, err)
+	}
+
+	// in flow style, the envelope form would be quoted
+	for _, p := range parents {
+		p.Style &^= yaml.FlowStyle
+	}
+
+	return nil
+}
+
+// encryptAll encrypts all scalar values of the given node structure in place. Mappings and sequences are changed to
+// block style, as the envelope form would be quoted in flow style.
+func encryptAll(node *yaml.Node, key []byte) error {
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode {
+		node.Style &^= yaml.FlowStyle
+	}
+
+	switch node.Kind { //nolint:exhaustive
+	case yaml.ScalarNode:
+		if isEncrypted(node) {
+			return nil
+		}
+
+		value, err := encryptNode(key,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: node.Tag, Style: node.Style, Value: node.Value})
+
+		if err != nil {
+			return err
+		}
+
+		node.Tag = "!!str"
+		node.Style = 0
+		node.Value = value
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := encryptAll(node.Content[i+1], key); err != nil {
+				return err
+			}
+		}
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range node.Content {
+			if err := encryptAll(c, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// EncryptFile encrypts the values at the given dotted paths of the YAML file in place, see [EncryptYAMLNode]. All
+// documents of the file are encrypted, a path has to be found in at least one of them. Comments, the order of the
+// keys and the indentation are retained.
+func EncryptFile(path string, key []byte, paths ...string) error {
+	path = filepath.Clean(path)
+	info, statErr := os.Stat(path)
+
+	if statErr != nil {
+		return fmt.Errorf("could not read %v: %w", path, statErr)
+	}
+
+	content, readErr := os.ReadFile(path)
+
+	if readErr != nil {
+		return fmt.Errorf("could not read %v: %w", path, readErr)
+	}
+
+	var documents []*yaml.Node
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+
+	for {
+		node := yaml.Node{}
+
+		if err := decoder.Decode(&node); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("could not parse %v: %w", path, err)
+		}
+
+		documents = append(documents, &node)
+	}
+
+	if err := encryptDocuments(documents, key, paths); err != nil {
+		return fmt.Errorf("could not encrypt %v: %w", path, err)
+	}
+
+	buf := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(detectIndent(content))
+
+	for _, node := range documents {
+		if err := encoder.Encode(node); err != nil {
+			return fmt.Errorf("could not encode %v: %w", path, err)
+		}
+	}
+
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("could not encode %v: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("could not write %v: %w", path, err)
+	}
+
+	return nil
+}
+
+// encryptDocuments encrypts the values at the given dotted paths in all given documents. Each path has to be found in
+// at least one of the documents.
+func encryptDocuments(documents []*yaml.Node, key []byte, paths []string) error {
+	if len(paths) == 0 {
+		for _, node := range documents {
+			if err := encryptAll(node, key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for _, p := range paths {
+		found := false
+
+		for _, node := range documents {
+			err := encryptPath(node, key, p)
+
+			if errors.Is(err, ErrValueNotFound) {
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			found = true
+		}
+
+		if !found {
+			return fmt.Errorf("%w: %v", ErrValueNotFound, p)
+		}
+	}
+
+	return nil
+}
+
+// detectIndent gives the indentation used in the given YAML content, that is the smallest indentation of its lines.
+// Without indented lines, the default of two spaces is given.
+func detectIndent(content []byte) int {
+	const (
+		defaultIndent = 2
+		maxIndent     = 9
+	)
+
+	result := 0
+
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		spaces := len(line) - len(trimmed)
+
+		if spaces == 0 || len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if result == 0 || spaces < result {
+			result = spaces
+		}
+	}
+
+	if result < defaultIndent || result > maxIndent {
+		return defaultIndent
+	}
+
+	return result
+}
+
+// decryptValues decrypts all encrypted values of the given node structure in place. The decrypted nodes belong to the
+// same layer and source as the encrypted ones. Values in the envelope form are only decrypted, if a key is given, so
+// that configurations without encrypted values may contain such strings.
+func (m *merger) decryptValues(node *yaml.Node) error {
+	if node == nil {
+		return nil
+	}
+
+	if !isTaggedEncrypted(node) && (len(m.key) == 0 || !isEnvelope(node)) {
+		var errs []error
+
+		for _, c := range node.Content {
+			errs = append(errs, m.decryptValues(c))
+		}
+
+		return errors.Join(errs...)
+	}
+
+	if len(m.key) == 0 {
+		return fmt.Errorf("%w for encrypted value in %v", ErrMissingKey, m.position(node))
+	}
+
+	plain, decryptErr := decrypt(m.key, node.Value)
+
+	if decryptErr != nil {
+		return fmt.Errorf("could not decrypt value in %v: %w", m.position(node), decryptErr)
+	}
+
+	decrypted := yaml.Node{}
+
+	if err := yaml.Unmarshal(plain, &decrypted); err != nil || len(decrypted.Content) != 1 {
+		return fmt.Errorf("%w: invalid value in %v", ErrDecryptionFailed, m.position(node))
+	}
+
+	value := decrypted.Content[0]
+	origin := m.origins[node]
+
+	node.Kind = value.Kind
+	node.Tag = value.Tag
+	node.Style = value.Style
+	node.Value = value.Value
+	node.Content = value.Content
+
+	if origin != nil {
+		for _, c := range node.Content {
+			if len(origin.source) > 0 {
+				m.markSource(c, origin.source)
+			}
+
+			m.markLayer(c, origin.layer)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,309 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/AlphaOne1/templig"
+)
+
+type TestEncryptedConfig struct {
+	Name     string `yaml:"name"`
+	Database struct {
+		Port  int      `yaml:"port"`
+		Pass  string   `yaml:"pass"`
+		Hosts []string `yaml:"hosts"`
+	} `yaml:"database"`
+}
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := templig.GenerateKey()
+
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+
+	return key
+}
+
+func TestEncryptedValues(t *testing.T) {
+	key := testKey(t)
+	pass, passErr := templig.EncryptValue(key, "0123")
+
+	if passErr != nil {
+		t.Fatalf("could not encrypt value: %v", passErr)
+	}
+
+	node := parseNode(t, "port: 5432\nhosts: [db0, db1]\n")
+
+	if err := templig.EncryptYAMLNode(node, key); err != nil {
+		t.Fatalf("could not encrypt node: %v", err)
+	}
+
+	database := encodeNode(t, node)
+
+	if strings.Contains(database, "5432") || strings.Contains(database, "db0") {
+		t.Fatalf("values not encrypted: %v", database)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{name: "envelope", in: "name: n\ndatabase:\n  pass: " + pass + "\n"},
+		{
+			name: "tag",
+			in:   "name: n\ndatabase:\n  pass: !encrypted " + strings.TrimSuffix(strings.TrimPrefix(pass, "ENC["), "]") + "\n",
+		},
+		{name: "template", in: "name: n\ndatabase:\n  pass: {{ print \"" + pass + "\" }}\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, loadErr := templig.Load[TestEncryptedConfig](
+				templig.WithReaders(strings.NewReader(test.in), strings.NewReader("database:\n"+indent(database))),
+				templig.WithDecryptionKey(key))
+
+			if loadErr != nil {
+				t.Fatalf("could not load configuration: %v", loadErr)
+			}
+
+			got := c.Get().Database
+
+			if got.Pass != "0123" || got.Port != 5432 || strings.Join(got.Hosts, ",") != "db0,db1" {
+				t.Errorf("unexpected decrypted values %+v", got)
+			}
+		})
+	}
+}
+
+func indent(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+
+	for i, l := range lines {
+		if len(l) > 0 {
+			lines[i] = "  " + l
+		}
+	}
+
+	return strings.Join(lines, "")
+}
+
+func TestEncryptedValuesErrors(t *testing.T) {
+	key := testKey(t)
+	pass, passErr := templig.EncryptValue(key, "secret")
+
+	if passErr != nil {
+		t.Fatalf("could not encrypt value: %v", passErr)
+	}
+
+	tagged := "!encrypted " + strings.TrimSuffix(strings.TrimPrefix(pass, "ENC["), "]")
+
+	tests := []struct {
+		name  string
+		value string
+		opts  []templig.Option
+		want  error
+	}{
+		{name: "missing key", value: tagged, want: templig.ErrMissingKey},
+		{name: "wrong key", value: pass, opts: []templig.Option{templig.WithDecryptionKey(testKey(t))},
+			want: templig.ErrDecryptionFailed},
+		{name: "short key", value: pass, opts: []templig.Option{templig.WithDecryptionKey(key[:16])},
+			want: templig.ErrInvalidKey},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := append([]templig.Option{templig.WithReaders(strings.NewReader("database:\n  pass: " + test.value))},
+				test.opts...)
+
+			_, loadErr := templig.Load[TestEncryptedConfig](opts...)
+
+			if !errors.Is(loadErr, test.want) {
+				t.Fatalf("expected %v but got %v", test.want, loadErr)
+			}
+
+			if !strings.Contains(loadErr.Error(), "line 2, column 9") {
+				t.Errorf("expected position of the value in %v", loadErr)
+			}
+		})
+	}
+}
+
+func TestEnvelopeFormPlain(t *testing.T) {
+	key := testKey(t)
+	pass, passErr := templig.EncryptValue(key, "secret")
+
+	if passErr != nil {
+		t.Fatalf("could not encrypt value: %v", passErr)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		opts []templig.Option
+		want string
+	}{
+		{name: "quoted without key", in: "'ENC[A-Z]'", want: "ENC[A-Z]"},
+		{name: "unquoted without key", in: "ENC[A-Z]", want: "ENC[A-Z]"},
+		{name: "quoted with key", in: "'" + pass + "'", opts: []templig.Option{templig.WithDecryptionKey(key)}, want: pass},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := append([]templig.Option{templig.WithReaders(strings.NewReader("database:\n  pass: " + test.in))},
+				test.opts...)
+
+			c, loadErr := templig.Load[TestEncryptedConfig](opts...)
+
+			if loadErr != nil {
+				t.Fatalf("could not load configuration: %v", loadErr)
+			}
+
+			if c.Get().Database.Pass != test.want {
+				t.Errorf("expected %v but got %v", test.want, c.Get().Database.Pass)
+			}
+		})
+	}
+}
+
+func TestEncryptFile(t *testing.T) {
+	dir := t.TempDir()
+	key := testKey(t)
+	keyFile := filepath.Join(dir, "key")
+	configFile := filepath.Join(dir, "config.yaml")
+
+	if err := os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600); err != nil {
+		t.Fatalf("could not write key: %v", err)
+	}
+
+	if err := os.WriteFile(configFile, []byte(`name: n # the name
+database:
+  port: 5432
+  pass: secret
+  hosts:
+    - db0
+    - db1
+`), 0o600); err != nil {
+		t.Fatalf("could not write configuration: %v", err)
+	}
+
+	if err := templig.EncryptFile(configFile, key, "database.pass", "database.hosts.1"); err != nil {
+		t.Fatalf("could not encrypt file: %v", err)
+	}
+
+	if err := templig.EncryptFile(configFile, key, "database.user"); !errors.Is(err, templig.ErrValueNotFound) {
+		t.Errorf("expected %v but got %v", templig.ErrValueNotFound, err)
+	}
+
+	content, readErr := os.ReadFile(configFile)
+
+	if readErr != nil {
+		t.Fatalf("could not read configuration: %v", readErr)
+	}
+
+	node := yaml.Node{}
+
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		t.Fatalf("could not parse configuration: %v", err)
+	}
+
+	var raw TestEncryptedConfig
+
+	if err := node.Decode(&raw); err != nil {
+		t.Fatalf("could not decode configuration: %v", err)
+	}
+
+	if !strings.HasPrefix(raw.Database.Pass, "ENC[") || !strings.HasPrefix(raw.Database.Hosts[1], "ENC[") ||
+		raw.Database.Hosts[0] != "db0" || raw.Database.Port != 5432 || !strings.Contains(string(content), "# the name") {
+		t.Errorf("unexpected encrypted file\n%v", string(content))
+	}
+
+	c, loadErr := templig.Load[TestEncryptedConfig](
+		templig.WithFiles(configFile),
+		templig.WithDecryptionKeyFile(keyFile))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration: %v", loadErr)
+	}
+
+	if got := c.Get().Database; got.Pass != "secret" || strings.Join(got.Hosts, ",") != "db0,db1" {
+		t.Errorf("unexpected decrypted values %+v", got)
+	}
+}
+
+func TestEncryptFileDocuments(t *testing.T) {
+	key := testKey(t)
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+
+	if err := os.WriteFile(configFile, []byte(`name: n
+database:
+    pass: a
+---
+profile: prod
+database:
+    pass: b
+`), 0o600); err != nil {
+		t.Fatalf("could not write configuration: %v", err)
+	}
+
+	if err := templig.EncryptFile(configFile, key, "database.pass"); err != nil {
+		t.Fatalf("could not encrypt file: %v", err)
+	}
+
+	if err := templig.EncryptFile(configFile, key, "profile"); err != nil {
+		t.Fatalf("could not encrypt value of second document: %v", err)
+	}
+
+	content, readErr := os.ReadFile(configFile)
+
+	if readErr != nil {
+		t.Fatalf("could not read configuration: %v", readErr)
+	}
+
+	if !strings.Contains(string(content), "\n    pass: ENC[") {
+		t.Errorf("indentation not retained\n%v", string(content))
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(string(content)))
+	var passes []string
+
+	for {
+		var raw TestEncryptedConfig
+
+		if err := decoder.Decode(&raw); err != nil {
+			break
+		}
+
+		passes = append(passes, raw.Database.Pass)
+	}
+
+	if len(passes) != 2 || !strings.HasPrefix(passes[0], "ENC[") || !strings.HasPrefix(passes[1], "ENC[") {
+		t.Fatalf("unexpected encrypted documents\n%v", string(content))
+	}
+
+	for i, want := range []string{"a", "b"} {
+		c, loadErr := templig.Load[TestEncryptedConfig](
+			templig.WithReaders(strings.NewReader("database:\n  pass: "+passes[i]+"\n")),
+			templig.WithDecryptionKey(key))
+
+		if loadErr != nil {
+			t.Fatalf("could not load configuration: %v", loadErr)
+		}
+
+		if got := c.Get().Database.Pass; got != want {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envLayerName is the source name of values read from environment variables.
+const envLayerName = "environment"
+
+// envOptions holds the settings of the environment variable layer.
+type envOptions struct {
+	prefix    string
+	separator string
+}
+
+// WithEnv adds a layer of environment variables on top of all configuration sources. Only variables starting with the
+// given prefix followed by an underscore are considered, e.g. `MYAPP_` for the prefix `MYAPP`. The rest of the name is
+// the path of the value, with double underscores separating the levels, so `MYAPP_DATABASE__URL` sets `database.url`.
+// The separator can be changed using [WithEnvSeparator]. Keys are matched case-insensitively against the configuration
+// type, variables not corresponding to a field are ignored. The values are converted according to the type of their
+// field. Lists are given comma-separated, replacing the lists of the configuration sources, or element-wise using their
+// index, e.g. `MYAPP_HOSTS__0`, replacing only the elements at these indices.
+func WithEnv(prefix string) Option {
+	return func(o *options) {
+		if o.env == nil {
+			o.env = &envOptions{separator: envLevelSeparator}
+		}
+
+		o.env.prefix = prefix
+	}
+}
+
+// WithEnvSeparator sets the separator of the levels in the names of environment variables, see [WithEnv].
+func WithEnvSeparator(separator string) Option {
+	return func(o *options) {
+		if o.env == nil {
+			o.env = &envOptions{separator: envLevelSeparator}
+		}
+
+		o.env.separator = separator
+	}
+}
+
+// envNode creates the node structure of the given environment variables for the configuration type t. If no variable
+// matches, nil is returned.
+func envNode(t reflect.Type, environ []string, env *envOptions) *yaml.Node {
+	root := containerNode(t)
+	prefix := env.prefix + "_"
+	found := false
+
+	// sorting gives a deterministic result, if the same value is given comma-separated and element-wise
+	environ = slices.Sorted(slices.Values(environ))
+
+	for _, variable := range environ {
+		name, value, _ := strings.Cut(variable, "=")
+
+		if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+			continue
+		}
+
+		path := strings.Split(strings.ToLower(name[len(prefix):]), env.separator)
+
+		if setTypedPath(root, t, path, value) {
+			found = true
+		}
+	}
+
+	if !found {
+		return nil
+	}
+
+	return documentNode(root)
+}
@@ -0,0 +1,115 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/templig"
+)
+
+type TestEnvDatabase struct {
+	URL      string        `yaml:"url"`
+	Port     int           `yaml:"port"`
+	TLS      bool          `yaml:"tls"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Replicas []string      `yaml:"replicas"`
+}
+
+type TestEnvConfig struct {
+	Name     string            `yaml:"name"`
+	Ratio    float64           `yaml:"ratio"`
+	Database TestEnvDatabase   `yaml:"database"`
+	Ports    []int             `yaml:"ports"`
+	Labels   map[string]string `yaml:"labels"`
+}
+
+func TestEnvOverlay(t *testing.T) {
+	t.Setenv("TEMPLIGTEST_NAME", "envName")
+	t.Setenv("TEMPLIGTEST_RATIO", "0.5")
+	t.Setenv("TEMPLIGTEST_DATABASE__URL", "postgres://db")
+	t.Setenv("TEMPLIGTEST_DATABASE__PORT", "5433")
+	t.Setenv("TEMPLIGTEST_DATABASE__TLS", "true")
+	t.Setenv("TEMPLIGTEST_DATABASE__TIMEOUT", "5s")
+	t.Setenv("TEMPLIGTEST_DATABASE__REPLICAS", "r0, r1")
+	t.Setenv("TEMPLIGTEST_PORTS__1", "8081")
+	t.Setenv("TEMPLIGTEST_LABELS__TEAM", "core")
+	t.Setenv("TEMPLIGTEST_UNKNOWN", "ignored")
+	t.Setenv("OTHER_NAME", "ignored")
+
+	c, loadErr := templig.Load[TestEnvConfig](
+		templig.WithReaders(strings.NewReader(
+			"name: fileName\n"+
+				"database:\n  url: postgres://file\n  port: 5432\n  replicas: [f0, f1, f2]\n"+
+				"ports: [80, 81, 82]\n")),
+		templig.WithEnv("TEMPLIGTEST"),
+	)
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration with environment: %v", loadErr)
+	}
+
+	got := c.Get()
+
+	if got.Name != "envName" || got.Ratio != 0.5 {
+		t.Errorf("unexpected top level values: %+v", got)
+	}
+
+	if got.Database.URL != "postgres://db" ||
+		got.Database.Port != 5433 ||
+		!got.Database.TLS ||
+		got.Database.Timeout != 5*time.Second ||
+		!slices.Equal(got.Database.Replicas, []string{"r0", "r1"}) {
+		t.Errorf("unexpected database values: %+v", got.Database)
+	}
+
+	if !slices.Equal(got.Ports, []int{80, 8081, 82}) {
+		t.Errorf("unexpected ports: %v", got.Ports)
+	}
+
+	if got.Labels["team"] != "core" {
+		t.Errorf("unexpected labels: %v", got.Labels)
+	}
+
+	if p, _ := c.Explain("database.port"); p.Source != "environment" || len(p.Overridden) != 1 {
+		t.Errorf("unexpected provenance of environment value: %v", p)
+	}
+}
+
+func TestEnvOverlaySeparator(t *testing.T) {
+	t.Setenv("TEMPLIGTEST_DATABASE_PORT", "1234")
+
+	c, loadErr := templig.Load[TestEnvConfig](templig.WithEnv("TEMPLIGTEST"), templig.WithEnvSeparator("_"))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration from environment only: %v", loadErr)
+	}
+
+	if c.Get().Database.Port != 1234 {
+		t.Errorf("expected port 1234 but got %v", c.Get().Database.Port)
+	}
+}
+
+func TestEnvOverlayEmpty(t *testing.T) {
+	c, loadErr := templig.Load[TestEnvConfig](templig.WithEnv("TEMPLIGTEST_NOT_SET"))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration from empty environment: %v", loadErr)
+	}
+
+	if c.Get().Name != "" {
+		t.Errorf("expected empty configuration but got %+v", c.Get())
+	}
+}
+
+func TestEnvOverlayWrongType(t *testing.T) {
+	t.Setenv("TEMPLIGTEST_DATABASE__PORT", "notANumber")
+
+	if _, loadErr := templig.Load[TestEnvConfig](templig.WithEnv("TEMPLIGTEST")); loadErr == nil {
+		t.Errorf("expected error for environment variable of wrong type")
+	}
+}
@@ -0,0 +1,387 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnknownField indicates that a configuration contains a key that has no corresponding field in the target type.
+var ErrUnknownField = errors.New("unknown field")
+
+// fieldInfo describes a struct field as it is seen by the YAML library.
+type fieldInfo struct {
+	key   string
+	index []int
+	typ   reflect.Type
+	tag   reflect.StructTag
+}
+
+// yamlFields gives the fields of the given struct type using the same naming rules as the YAML library. Fields of
+// inlined structures are included. The second result indicates, if the type contains an inlined map, that accepts
+// arbitrary keys.
+func yamlFields(t reflect.Type) ([]fieldInfo, bool) {
+	var result []fieldInfo
+	inlineMap := false
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("yaml")
+
+		if tag == "" && !strings.Contains(string(field.Tag), ":") {
+			tag = string(field.Tag)
+		}
+
+		if tag == "-" {
+			continue
+		}
+
+		name, flags, _ := strings.Cut(tag, ",")
+
+		if slices.Contains(strings.Split(flags, ","), "inline") {
+			ft := derefType(field.Type)
+
+			switch ft.Kind() { //nolint:exhaustive
+			case reflect.Map:
+				inlineMap = true
+			case reflect.Struct:
+				inner, innerMap := yamlFields(ft)
+
+				for _, f := range inner {
+					f.index = append([]int{i}, f.index...)
+					result = append(result, f)
+				}
+
+				inlineMap = inlineMap || innerMap
+			}
+
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		result = append(result, fieldInfo{
+			key:   name,
+			index: []int{i},
+			typ:   field.Type,
+			tag:   field.Tag,
+		})
+	}
+
+	return result, inlineMap
+}
+
+// findField searches the field with the given YAML key.
+func findField(fields []fieldInfo, key string) (fieldInfo, bool) {
+	for _, f := range fields {
+		if f.key == key {
+			return f, true
+		}
+	}
+
+	return fieldInfo{}, false
+}
+
+// unmarshalerType is the type of the yaml.Unmarshaler interface.
+var unmarshalerType = reflect.TypeFor[yaml.Unmarshaler]() //nolint:gochecknoglobals
+
+// derefType removes all pointer indirections and [Secret] wrappers of the given type, so secrets are handled like
+// the values they hold.
+func derefType(t reflect.Type) reflect.Type {
+	for {
+		switch {
+		case t.Kind() == reflect.Pointer:
+			t = t.Elem()
+		case t.Implements(secretValueType):
+			t = reflect.Zero(t).Interface().(secretValue).secretType() //nolint:forcetypeassert
+		default:
+			return t
+		}
+	}
+}
+
+// checkKnownFields checks that all keys of the given node have a corresponding field in the given type. All
+// violations are reported with their full path and source location.
+func checkKnownFields(node *yaml.Node, t reflect.Type, path string, sourceOf func(*yaml.Node) string) error {
+	if node == nil {
+		return nil
+	}
+
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	if node.Kind == yaml.DocumentNode {
+		var errs []error
+
+		for _, v := range node.Content {
+			errs = append(errs, checkKnownFields(v, t, path, sourceOf))
+		}
+
+		return errors.Join(errs...)
+	}
+
+	t = derefType(t)
+
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	var errs []error
+
+	switch t.Kind() { //nolint:exhaustive
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		fields, inlineMap := yamlFields(t)
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			value := node.Content[i+1]
+
+			if key.Tag == "!!merge" {
+				errs = append(errs, checkKnownFields(value, t, path, sourceOf))
+
+				continue
+			}
+
+			keyPath := joinPath(path, key.Value)
+
+			if f, found := findField(fields, key.Value); found {
+				errs = append(errs, checkKnownFields(value, f.typ, keyPath, sourceOf))
+			} else if !inlineMap {
+				errs = append(errs, fmt.Errorf("%w %v in %v, line %v, column %v",
+					ErrUnknownField, keyPath, sourceOf(key), key.Line, key.Column))
+			}
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			errs = append(errs,
+				checkKnownFields(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value), sourceOf))
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+
+		for i, v := range node.Content {
+			errs = append(errs, checkKnownFields(v, t.Elem(), joinPath(path, strconv.Itoa(i)), sourceOf))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// durationType is the type of time.Duration, that is given as string in YAML.
+var durationType = reflect.TypeFor[time.Duration]() //nolint:gochecknoglobals
+
+// scalarTag gives the YAML tag of scalars of the given type. For types without a corresponding tag, the empty tag is
+// given, so the type is resolved by the YAML library.
+func scalarTag(t reflect.Type) string {
+	t = derefType(t)
+
+	if t == durationType {
+		return "!!str"
+	}
+
+	switch t.Kind() { //nolint:exhaustive
+	case reflect.Bool:
+		return "!!bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "!!int"
+	case reflect.Float32, reflect.Float64:
+		return "!!float"
+	case reflect.String:
+		return "!!str"
+	default:
+		return ""
+	}
+}
+
+// isListType checks if values of the given type are given as YAML sequence.
+func isListType(t reflect.Type) bool {
+	t = derefType(t)
+
+	return (t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8) || t.Kind() == reflect.Array
+}
+
+// typedValueNode converts the given textual value to a node of the given type. Values of list types are given
+// comma-separated, they replace the underlying lists when merged.
+func typedValueNode(t reflect.Type, value string) *yaml.Node {
+	if isListType(t) {
+		result := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!" + string(SequenceReplace)}
+
+		if len(value) == 0 {
+			return result
+		}
+
+		for _, v := range strings.Split(value, ",") {
+			result.Content = append(result.Content, typedValueNode(derefType(t).Elem(), strings.TrimSpace(v)))
+		}
+
+		return result
+	}
+
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: scalarTag(t), Value: value}
+}
+
+// containerNode creates an empty node suitable to hold the values of the given type.
+func containerNode(t reflect.Type) *yaml.Node {
+	if isListType(t) {
+		return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+// setTypedPath sets the value at the given path of the node structure representing the type t, creating intermediate
+// nodes as necessary. Keys of structures are matched case-insensitively, elements of lists are addressed by their
+// index. The textual value is converted according to the type found at the path. If the path does not exist in the
+// type, false is returned.
+func setTypedPath(node *yaml.Node, t reflect.Type, path []string, value string) bool {
+	t = derefType(t)
+
+	if len(path) == 0 || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return false
+	}
+
+	var key string
+	var childType reflect.Type
+
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields, _ := yamlFields(t)
+		index := slices.IndexFunc(fields, func(f fieldInfo) bool { return strings.EqualFold(f.key, path[0]) })
+
+		if index < 0 {
+			return false
+		}
+
+		key = fields[index].key
+		childType = fields[index].typ
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		key = path[0]
+		childType = t.Elem()
+	case isListType(t) && node.Kind == yaml.SequenceNode:
+		index, err := strconv.Atoi(path[0])
+
+		if err != nil || index < 0 || (t.Kind() == reflect.Array && index >= t.Len()) {
+			return false
+		}
+
+		for len(node.Content) <= index {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"})
+		}
+
+		// lists given element-wise are merged at the indices of their elements, lists given as a whole are replaced
+		if node.Tag != "!"+string(SequenceReplace) {
+			node.Tag = "!" + string(sequenceIndex)
+		}
+
+		if len(path) == 1 {
+			node.Content[index] = typedValueNode(t.Elem(), value)
+
+			return true
+		}
+
+		if node.Content[index].ShortTag() == "!!null" {
+			node.Content[index] = containerNode(t.Elem())
+		}
+
+		return setTypedPath(node.Content[index], t.Elem(), path[1:], value)
+	default:
+		return false
+	}
+
+	return setTypedKey(node, key, childType, path[1:], value)
+}
+
+// setTypedKey sets the value of the given key in the mapping node, see setTypedPath.
+func setTypedKey(node *yaml.Node, key string, t reflect.Type, path []string, value string) bool {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != key {
+			continue
+		}
+
+		if len(path) == 0 {
+			node.Content[i+1] = typedValueNode(t, value)
+
+			return true
+		}
+
+		return setTypedPath(node.Content[i+1], t, path, value)
+	}
+
+	var child *yaml.Node
+
+	if len(path) == 0 {
+		child = typedValueNode(t, value)
+	} else {
+		child = containerNode(t)
+
+		if !setTypedPath(child, t, path, value) {
+			return false
+		}
+	}
+
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+
+	return true
+}
+
+// walkFields calls the given function for all fields of the given struct type and its substructures, giving their
+// dotted path. Fields of types with custom YAML decoding are not descended into, neither are structures of recursive
+// types already walked on the path.
+func walkFields(t reflect.Type, path string, f func(path string, field fieldInfo)) {
+	walkTypeFields(t, path, f, make(map[reflect.Type]bool))
+}
+
+// walkTypeFields walks the fields like walkFields, skipping the given struct types currently walked.
+func walkTypeFields(t reflect.Type, path string, f func(path string, field fieldInfo), walking map[reflect.Type]bool) {
+	t = derefType(t)
+
+	if t.Kind() != reflect.Struct || reflect.PointerTo(t).Implements(unmarshalerType) || walking[t] {
+		return
+	}
+
+	walking[t] = true
+	defer delete(walking, t)
+
+	fields, _ := yamlFields(t)
+
+	for _, field := range fields {
+		fieldPath := joinPath(path, field.key)
+
+		f(fieldPath, field)
+		walkTypeFields(field.typ, fieldPath, f, walking)
+	}
+}
+
+// hasTagOption checks if the `templig` tag of a field contains the given option, e.g. `secret` in
+// `templig:"secret"`.
+func hasTagOption(tag reflect.StructTag, option string) bool {
+	return slices.Contains(strings.Split(tag.Get("templig"), ","), option)
+}
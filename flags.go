@@ -0,0 +1,117 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// flagsLayerName is the source name of values read from command line flags.
+const flagsLayerName = "command line"
+
+// configFlag is a command line flag setting a configuration value.
+type configFlag struct {
+	path   []string
+	list   bool
+	isBool bool
+	value  string
+	set    bool
+}
+
+// String gives the value of the flag.
+func (f *configFlag) String() string {
+	if f == nil {
+		return ""
+	}
+
+	return f.value
+}
+
+// Set sets the value of the flag. Flags of list types can be given repeatedly, the values are collected.
+func (f *configFlag) Set(value string) error {
+	if f.list && f.set && len(value) > 0 {
+		f.value += "," + value
+	} else {
+		f.value = value
+	}
+
+	f.set = true
+
+	return nil
+}
+
+// IsBoolFlag allows boolean flags to be given without value.
+func (f *configFlag) IsBoolFlag() bool {
+	return f.isBool
+}
+
+// flaggable checks if a command line flag can be generated for fields of the given type.
+func flaggable(t reflect.Type) bool {
+	if isListType(t) {
+		t = derefType(t).Elem()
+	}
+
+	t = derefType(t)
+
+	return scalarTag(t) != "" && !reflect.PointerTo(t).Implements(unmarshalerType)
+}
+
+// RegisterFlags registers command line flags for all values of the configuration type T on the given flag.FlagSet.
+// The flags are named like the dotted path of the value, e.g. `--database.url`. The usage text of a flag is taken from
+// the `usage` tag of the field, if present. Flags of list types take comma-separated values or can be given repeatedly.
+// After parsing the command line, [WithFlags] applies the given flags as final layer of the configuration.
+//
+// To use the flags with github.com/spf13/pflag, register them on a flag.FlagSet and add it using
+// pflag.CommandLine.AddGoFlagSet.
+func RegisterFlags[T any](set *flag.FlagSet) {
+	walkFields(reflect.TypeFor[T](), "", func(path string, field fieldInfo) {
+		if !flaggable(field.typ) {
+			return
+		}
+
+		usage := field.tag.Get("usage")
+
+		if len(usage) == 0 {
+			usage = "sets " + path
+		}
+
+		set.Var(&configFlag{
+			path:   strings.Split(path, "."),
+			list:   isListType(field.typ),
+			isBool: derefType(field.typ).Kind() == reflect.Bool,
+		}, path, usage)
+	})
+}
+
+// WithFlags adds the command line flags registered using [RegisterFlags] on the given flag.FlagSet as final layer on
+// top of all other configuration sources. Only the flags given on the command line are applied, so the flag.FlagSet
+// has to be parsed before loading the configuration. Lists given by flags replace the lists of all other layers.
+func WithFlags(set *flag.FlagSet) Option {
+	return func(o *options) {
+		o.flags = set
+	}
+}
+
+// flagsNode creates the node structure of the command line flags set in the given flag.FlagSet for the configuration
+// type t. If no flag was set, nil is returned.
+func flagsNode(t reflect.Type, set *flag.FlagSet) *yaml.Node {
+	root := containerNode(t)
+	found := false
+
+	set.Visit(func(f *flag.Flag) {
+		if cf, ok := f.Value.(*configFlag); ok && setTypedPath(root, t, cf.path, cf.value) {
+			found = true
+		}
+	})
+
+	if !found {
+		return nil
+	}
+
+	return documentNode(root)
+}
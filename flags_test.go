@@ -0,0 +1,155 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/templig"
+)
+
+type TestFlagsDatabase struct {
+	URL      string        `yaml:"url" usage:"URL of the database"`
+	Port     int           `yaml:"port"`
+	TLS      bool          `yaml:"tls"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Replicas []string      `yaml:"replicas"`
+}
+
+type TestFlagsConfig struct {
+	Name     string            `yaml:"name"`
+	Database TestFlagsDatabase `yaml:"database"`
+	Labels   map[string]string `yaml:"labels"`
+}
+
+func newTestFlagSet(t *testing.T, args ...string) *flag.FlagSet {
+	t.Helper()
+
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	set.Bool("verbose", false, "not a configuration flag")
+
+	templig.RegisterFlags[TestFlagsConfig](set)
+
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("could not parse flags: %v", err)
+	}
+
+	return set
+}
+
+func TestFlagsOverlay(t *testing.T) {
+	t.Setenv("TEMPLIGTEST_NAME", "envName")
+	t.Setenv("TEMPLIGTEST_DATABASE__PORT", "5433")
+	t.Setenv("TEMPLIGTEST_DATABASE__REPLICAS", "e0")
+
+	set := newTestFlagSet(t,
+		"--name=flagName",
+		"--verbose",
+		"--database.tls",
+		"--database.timeout", "5s",
+		"--database.replicas=r0",
+		"--database.replicas=r1,r2",
+	)
+
+	c, loadErr := templig.Load[TestFlagsConfig](
+		templig.WithReaders(strings.NewReader(
+			"name: fileName\ndatabase:\n  url: postgres://file\n  port: 5432\n  replicas: [f0, f1]\n")),
+		templig.WithEnv("TEMPLIGTEST"),
+		templig.WithFlags(set),
+	)
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration with flags: %v", loadErr)
+	}
+
+	got := c.Get()
+
+	if got.Name != "flagName" {
+		t.Errorf("expected flag to take precedence over environment, got %v", got.Name)
+	}
+
+	if got.Database.URL != "postgres://file" ||
+		got.Database.Port != 5433 ||
+		!got.Database.TLS ||
+		got.Database.Timeout != 5*time.Second ||
+		!slices.Equal(got.Database.Replicas, []string{"r0", "r1", "r2"}) {
+		t.Errorf("unexpected database values: %+v", got.Database)
+	}
+
+	if p, _ := c.Explain("name"); p.Source != "command line" || len(p.Overridden) != 2 {
+		t.Errorf("unexpected provenance of flag value: %v", p)
+	}
+}
+
+func TestFlagsOnly(t *testing.T) {
+	c, loadErr := templig.Load[TestFlagsConfig](templig.WithFlags(newTestFlagSet(t, "--database.port", "1234")))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration from flags only: %v", loadErr)
+	}
+
+	if c.Get().Database.Port != 1234 {
+		t.Errorf("expected port 1234 but got %v", c.Get().Database.Port)
+	}
+}
+
+func TestFlagsWrongType(t *testing.T) {
+	set := newTestFlagSet(t, "--database.port", "notANumber")
+
+	if _, loadErr := templig.Load[TestFlagsConfig](templig.WithFlags(set)); loadErr == nil {
+		t.Errorf("expected error for flag of wrong type")
+	}
+}
+
+func TestFlagsUsage(t *testing.T) {
+	set := newTestFlagSet(t)
+	buf := bytes.Buffer{}
+
+	set.SetOutput(&buf)
+	set.PrintDefaults()
+
+	for _, expected := range []string{
+		"-database.url value\n    \tURL of the database",
+		"-database.port value\n    \tsets database.port",
+		"-database.tls\n",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected usage to contain %q, got:\n%v", expected, buf.String())
+		}
+	}
+
+	if strings.Contains(buf.String(), "labels") {
+		t.Errorf("no flags expected for maps, got:\n%v", buf.String())
+	}
+}
+
+func TestFlagsRecursive(t *testing.T) {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.SetOutput(io.Discard)
+
+	templig.RegisterFlags[TestRecursiveConfig](set)
+
+	if err := set.Parse([]string{"--name=flagName"}); err != nil {
+		t.Fatalf("could not parse flags: %v", err)
+	}
+
+	c, loadErr := templig.Load[TestRecursiveConfig](
+		templig.WithReaders(strings.NewReader("next:\n  name: second\n")),
+		templig.WithFlags(set))
+
+	if loadErr != nil {
+		t.Fatalf("could not load recursive configuration: %v", loadErr)
+	}
+
+	if c.Get().Name != "flagName" || c.Get().Next == nil || c.Get().Next.Name != "second" {
+		t.Errorf("unexpected configuration: %+v", c.Get())
+	}
+}
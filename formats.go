@@ -0,0 +1,323 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidEnvLine indicates that a line of a dotenv file is not of the form `KEY=value`.
+var ErrInvalidEnvLine = errors.New("invalid dotenv line")
+
+// Format is a format of configuration sources. All formats are converted to the YAML node structure, so sources of
+// different formats can be overlaid freely.
+type Format int
+
+const (
+	// FormatAuto detects the format using the file extension, defaulting to YAML.
+	FormatAuto Format = iota
+	// FormatYAML is the YAML format.
+	FormatYAML
+	// FormatJSON is the JSON format.
+	FormatJSON
+	// FormatTOML is the TOML format.
+	FormatTOML
+	// FormatEnv is the dotenv format with lines of the form `KEY=value`. Keys are converted to lower case, double
+	// underscores separate the levels of the configuration, e.g. `DATABASE__URL` is converted to `database.url`.
+	FormatEnv
+)
+
+// envLevelSeparator separates the levels of the configuration in keys of the dotenv format.
+const envLevelSeparator = "__"
+
+// String gives the name of the format.
+func (f Format) String() string {
+	switch f {
+	case FormatAuto:
+		return "auto"
+	case FormatYAML:
+		return "yaml"
+	case FormatJSON:
+		return "json"
+	case FormatTOML:
+		return "toml"
+	case FormatEnv:
+		return "env"
+	default:
+		return "unknown format " + strconv.Itoa(int(f))
+	}
+}
+
+// formatOf gives the format of the file with the given name using its extension.
+func formatOf(name string) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	case ".env":
+		return FormatEnv
+	default:
+		return FormatYAML
+	}
+}
+
+// parseNodes parses the given content of the given format to YAML node structures, one per document. Only YAML
+// supports multiple documents, separated by `---`, empty documents of those are left out.
+func parseNodes(content []byte, format Format) ([]*yaml.Node, error) {
+	switch format {
+	case FormatTOML:
+		node, err := parseTOML(content)
+
+		return []*yaml.Node{node}, err
+	case FormatEnv:
+		node, err := parseEnv(content)
+
+		return []*yaml.Node{node}, err
+	case FormatAuto, FormatYAML, FormatJSON:
+		// JSON is a subset of YAML, so the YAML parser keeps the source positions for JSON too.
+		var result []*yaml.Node
+
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+
+		for {
+			var node yaml.Node
+
+			if err := decoder.Decode(&node); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("could not parse configuration: %w", err)
+			}
+
+			result = append(result, &node)
+		}
+
+		if len(result) > 1 {
+			result = slices.DeleteFunc(result, emptyDocument)
+		}
+
+		if len(result) == 0 {
+			// empty content, e.g. only comments
+			result = append(result, &yaml.Node{Kind: yaml.DocumentNode})
+		}
+
+		return result, nil
+	default:
+		return nil, fmt.Errorf("could not parse configuration: unhandled %v", format)
+	}
+}
+
+// emptyDocument checks if the given document node has no content.
+func emptyDocument(node *yaml.Node) bool {
+	return len(node.Content) == 0 ||
+		(len(node.Content) == 1 && node.Content[0].Kind == yaml.ScalarNode && node.Content[0].ShortTag() == "!!null")
+}
+
+// documentNode wraps the given node in a document node.
+func documentNode(content *yaml.Node) *yaml.Node {
+	return &yaml.Node{
+		Kind:    yaml.DocumentNode,
+		Content: []*yaml.Node{content},
+	}
+}
+
+// parseTOML parses the given TOML content to a YAML node structure, keeping the order of the keys.
+func parseTOML(content []byte) (*yaml.Node, error) {
+	var values map[string]any
+
+	meta, err := toml.Decode(string(content), &values)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse configuration: %w", err)
+	}
+
+	order := make(map[string]int)
+
+	for i, k := range meta.Keys() {
+		order[k.String()] = i
+	}
+
+	node, nodeErr := tomlNode(values, "", order)
+
+	if nodeErr != nil {
+		return nil, nodeErr
+	}
+
+	return documentNode(node), nil
+}
+
+// tomlNode converts a decoded TOML value to a YAML node structure. Tables are ordered like in the source.
+func tomlNode(value any, path string, order map[string]int) (*yaml.Node, error) {
+	table, isTable := value.(map[string]any)
+
+	if !isTable {
+		var node yaml.Node
+
+		if err := node.Encode(value); err != nil {
+			return nil, fmt.Errorf("could not convert %v: %w", path, err)
+		}
+
+		return &node, nil
+	}
+
+	keys := make([]string, 0, len(table))
+
+	for k := range table {
+		keys = append(keys, k)
+	}
+
+	slices.SortFunc(keys, func(a, b string) int {
+		return order[joinPath(path, a)] - order[joinPath(path, b)]
+	})
+
+	result := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+
+	for _, k := range keys {
+		v, vErr := tomlNode(table[k], joinPath(path, k), order)
+
+		if vErr != nil {
+			return nil, vErr
+		}
+
+		result.Content = append(result.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k},
+			v)
+	}
+
+	return result, nil
+}
+
+// parseEnv parses the given dotenv content to a YAML node structure.
+func parseEnv(content []byte) (*yaml.Node, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: 1, Column: 1}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+
+		if !found || len(key) == 0 {
+			return nil, fmt.Errorf("%w %v: %v", ErrInvalidEnvLine, lineNum, line)
+		}
+
+		valueNode, valueErr := envValueNode(strings.TrimSpace(value))
+
+		if valueErr != nil {
+			return nil, fmt.Errorf("%w %v: %w", ErrInvalidEnvLine, lineNum, valueErr)
+		}
+
+		valueNode.Line = lineNum
+		valueNode.Column = strings.Index(line, "=") + 2 //nolint:mnd
+
+		setPath(root, strings.Split(strings.ToLower(key), envLevelSeparator), valueNode, lineNum)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read dotenv content: %w", err)
+	}
+
+	indexedSequences(root)
+
+	return documentNode(root), nil
+}
+
+// indexedSequences converts the mappings of the given node structure, whose keys are the indices 0 to n-1, to
+// sequences, so that e.g. `PASSES__0` and `PASSES__1` give a sequence like the one written by [FormatEnv].
+func indexedSequences(node *yaml.Node) {
+	for i := 1; i < len(node.Content); i += 2 {
+		indexedSequences(node.Content[i])
+	}
+
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+
+	elements := make([]*yaml.Node, len(node.Content)/2) //nolint:mnd
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		index, err := strconv.Atoi(node.Content[i].Value)
+
+		if err != nil || index < 0 || index >= len(elements) || elements[index] != nil ||
+			strconv.Itoa(index) != node.Content[i].Value {
+			return
+		}
+
+		elements[index] = node.Content[i+1]
+	}
+
+	node.Kind = yaml.SequenceNode
+	node.Tag = "!!seq"
+	node.Content = elements
+}
+
+// envValueNode creates a scalar node of the given dotenv value. Quoted values are always strings, the type of unquoted
+// values is resolved like in YAML.
+func envValueNode(value string) (*yaml.Node, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		unquoted, err := strconv.Unquote(value)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted value: %w", err)
+		}
+
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: yaml.DoubleQuotedStyle, Value: unquoted}, nil
+	case strings.HasPrefix(value, `'`) && strings.HasSuffix(value, `'`) && len(value) > 1:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: yaml.SingleQuotedStyle,
+			Value: value[1 : len(value)-1]}, nil
+	default:
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: value}, nil
+	}
+}
+
+// setPath sets the value at the given path of the mapping node, creating intermediate mappings as necessary.
+func setPath(node *yaml.Node, path []string, value *yaml.Node, line int) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != path[0] {
+			continue
+		}
+
+		if len(path) > 1 && node.Content[i+1].Kind == yaml.MappingNode {
+			setPath(node.Content[i+1], path[1:], value, line)
+		} else if len(path) == 1 {
+			node.Content[i+1] = value
+		}
+
+		return
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path[0], Line: line, Column: 1}
+
+	if len(path) == 1 {
+		node.Content = append(node.Content, key, value)
+
+		return
+	}
+
+	child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: line, Column: 1}
+	node.Content = append(node.Content, key, child)
+
+	setPath(child, path[1:], value, line)
+}
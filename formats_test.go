@@ -0,0 +1,150 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/AlphaOne1/templig"
+)
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []templig.Option
+		want    TestConfig
+		wantErr bool
+	}{
+		{ // 0
+			name: "yaml base json overlay",
+			opts: []templig.Option{
+				templig.WithFiles("testData/test_config_0.yaml", "testData/test_config_0_overlay.json"),
+			},
+			want: TestConfig{
+				ID:   9,
+				Name: "NameJSON",
+				Conn: &TestConn{URL: "https://www.tests.to", Passes: []string{"pass0", "pass1", "passJSON"}},
+			},
+		},
+		{ // 1
+			name: "yaml base toml overlay",
+			opts: []templig.Option{
+				templig.WithFiles("testData/test_config_0.yaml", "testData/test_config_0_overlay.toml"),
+			},
+			want: TestConfig{
+				ID:   9,
+				Name: "NameTOML",
+				Conn: &TestConn{URL: "https://toml.tests.to", Passes: []string{"pass0", "pass1", "passTOML"}},
+			},
+		},
+		{ // 2
+			name: "yaml base env overlay",
+			opts: []templig.Option{
+				templig.WithFiles("testData/test_config_0.yaml", "testData/test_config_0_overlay.env"),
+			},
+			want: TestConfig{
+				ID:   10,
+				Name: "Name Env",
+				Conn: &TestConn{URL: "https://env.tests.to", Passes: []string{"pass0", "pass1"}},
+			},
+		},
+		{ // 3
+			name: "explicit format of reader",
+			opts: []templig.Option{
+				templig.WithReaders(strings.NewReader("id = 3\nname = \"{{ \"templated\" | upper }}\"")),
+				templig.WithFormat(templig.FormatTOML),
+			},
+			want: TestConfig{
+				ID:   3,
+				Name: "TEMPLATED",
+			},
+		},
+		{ // 4
+			name: "invalid toml",
+			opts: []templig.Option{
+				templig.WithReaders(strings.NewReader("id = = 3")),
+				templig.WithFormat(templig.FormatTOML),
+			},
+			wantErr: true,
+		},
+		{ // 5
+			name: "indexed env keys",
+			opts: []templig.Option{
+				templig.WithReaders(strings.NewReader("CONN__PASSES__1=pass1\nCONN__PASSES__0=pass0\n")),
+				templig.WithFormat(templig.FormatEnv),
+			},
+			want: TestConfig{
+				Conn: &TestConn{Passes: []string{"pass0", "pass1"}},
+			},
+		},
+		{ // 6
+			name: "invalid env",
+			opts: []templig.Option{
+				templig.WithReaders(strings.NewReader("ID")),
+				templig.WithFormat(templig.FormatEnv),
+			},
+			wantErr: true,
+		},
+	}
+
+	for testNum, test := range tests {
+		c, loadErr := templig.Load[TestConfig](test.opts...)
+
+		if test.wantErr != (loadErr != nil) {
+			t.Errorf("%v - %v: wanted error %v but got %v", testNum, test.name, test.wantErr, loadErr)
+
+			continue
+		}
+
+		if loadErr != nil {
+			continue
+		}
+
+		if c.Get().ID != test.want.ID || c.Get().Name != test.want.Name {
+			t.Errorf("%v - %v: wanted %+v but got %+v", testNum, test.name, test.want, c.Get())
+		}
+
+		if (c.Get().Conn == nil) != (test.want.Conn == nil) {
+			t.Errorf("%v - %v: wanted conn %v but got %v", testNum, test.name, test.want.Conn, c.Get().Conn)
+
+			continue
+		}
+
+		if c.Get().Conn != nil &&
+			(c.Get().Conn.URL != test.want.Conn.URL || !slices.Equal(c.Get().Conn.Passes, test.want.Conn.Passes)) {
+			t.Errorf("%v - %v: wanted conn %+v but got %+v", testNum, test.name, test.want.Conn, c.Get().Conn)
+		}
+	}
+}
+
+func TestFormatEnvErrors(t *testing.T) {
+	_, loadErr := templig.Load[TestConfig](
+		templig.WithReaders(strings.NewReader("# comment\nNAME=\"unterminated\n")),
+		templig.WithFormat(templig.FormatEnv),
+	)
+
+	if !errors.Is(loadErr, templig.ErrInvalidEnvLine) {
+		t.Errorf("expected error %v but got %v", templig.ErrInvalidEnvLine, loadErr)
+	}
+}
+
+func TestFormatString(t *testing.T) {
+	formats := map[templig.Format]string{
+		templig.FormatAuto: "auto",
+		templig.FormatYAML: "yaml",
+		templig.FormatJSON: "json",
+		templig.FormatTOML: "toml",
+		templig.FormatEnv:  "env",
+		templig.Format(99): "unknown format 99",
+	}
+
+	for format, want := range formats {
+		if format.String() != want {
+			t.Errorf("wanted format name %v but got %v", want, format.String())
+		}
+	}
+}
@@ -4,11 +4,13 @@
 package templig
 
 import (
+	"context"
 	"errors"
 	"io"
 	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
 	"slices"
 	"strings"
 	"text/template"
@@ -21,9 +23,11 @@ import (
 // that is guaranteed to never be used as a templig provided function.
 var TemplateFunctions = template.FuncMap{ //nolint:gochecknoglobals
 	"arg":      argumentValue,
+	"context":  contextValue,
 	"hasArg":   argumentPresent,
 	"required": required,
 	"read":     readFile,
+	"secret":   lookupSecret,
 }
 
 // templigFunctions gives all the functions that are enabled for the templating engine.
@@ -35,6 +39,20 @@ func templigFunctions() template.FuncMap {
 	return result
 }
 
+// replaceFunc replaces the template function with the given name by the replacement, if it is still the given
+// original. Functions that were replaced by the user are kept.
+func replaceFunc(funcs template.FuncMap, name string, original any, replacement any) {
+	if f, found := funcs[name]; found && reflect.ValueOf(f).Pointer() == reflect.ValueOf(original).Pointer() {
+		funcs[name] = replacement
+	}
+}
+
+// contextValue is a template function giving the context of the loading process, see [WithContext]. Outside a loading
+// process, the background context is given.
+func contextValue() context.Context {
+	return context.Background()
+}
+
 // required is a template function to indicate that the second argument cannot be empty or nil.
 func required(warn string, val any) (any, error) {
 	if s, ok := val.(string); val == nil || (ok && s == "") {
@@ -48,7 +66,14 @@ func required(warn string, val any) (any, error) {
 // If the file does not exist, an empty string is generated, facilitating the use of `required` for customized
 // user interaction.
 func readFile(fileName string) (any, error) {
-	file, err := os.Open(filepath.Clean(fileName))
+	return readFileWith(func(name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Clean(name))
+	}, fileName)
+}
+
+// readFileWith reads a file like readFile, using the given function to open it.
+func readFileWith(open func(string) (io.ReadCloser, error), fileName string) (any, error) {
+	file, err := open(fileName)
 
 	if err != nil {
 		return "", nil
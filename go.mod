@@ -6,6 +6,7 @@ module github.com/AlphaOne1/templig
 go 1.23.0
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/Masterminds/sprig/v3 v3.3.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,122 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrIncludeCycle indicates that a file includes itself, directly or indirectly, using the `!include` tag.
+	ErrIncludeCycle = errors.New("include cycle")
+
+	// ErrInvalidInclude indicates that an `!include` tag is not used on a file name or the included file does not
+	// contain exactly one document.
+	ErrInvalidInclude = errors.New("invalid include")
+)
+
+// includeTag is the tag replacing a scalar by the content of the file it names.
+const includeTag = "!include"
+
+// includer resolves the `!include` tags of configuration sources.
+type includer struct {
+	o *options
+	m *merger
+
+	// stack contains the files currently included, to detect cycles
+	stack []string
+}
+
+// includeKey gives the key used to detect cycles for the given file.
+func (in *includer) includeKey(name string) string {
+	if in.o.fsys != nil {
+		return filepath.Clean(name)
+	}
+
+	if abs, err := filepath.Abs(name); err == nil {
+		return abs
+	}
+
+	return filepath.Clean(name)
+}
+
+// resolve replaces the children of the given node tagged using `!include` by the content of the named files. Relative
+// file names are resolved against the given directory.
+func (in *includer) resolve(node *yaml.Node, dir string) error {
+	for i, c := range node.Content {
+		if c.Tag != includeTag {
+			if err := in.resolve(c, dir); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if c.Kind != yaml.ScalarNode || len(c.Value) == 0 {
+			return fmt.Errorf("%w in line %v, column %v: file name expected", ErrInvalidInclude, c.Line, c.Column)
+		}
+
+		name := c.Value
+
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+
+		included, includeErr := in.include(name)
+
+		if includeErr != nil {
+			return fmt.Errorf("could not include %v in line %v, column %v: %w", name, c.Line, c.Column, includeErr)
+		}
+
+		node.Content[i] = included
+	}
+
+	return nil
+}
+
+// include reads the given file, runs its template functions and resolves its own includes.
+func (in *includer) include(name string) (*yaml.Node, error) {
+	key := in.includeKey(name)
+
+	if slices.Contains(in.stack, key) {
+		return nil, fmt.Errorf("%w: %v", ErrIncludeCycle, strings.Join(append(in.stack, key), " -> "))
+	}
+
+	in.stack = append(in.stack, key)
+	defer func() { in.stack = in.stack[:len(in.stack)-1] }()
+
+	in.o.files = append(in.o.files, name)
+
+	content, readErr := in.o.fileSource(name).read(in.o.ctx)
+
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	docs, docsErr := fromSingle(bytes.NewReader(content), in.o.funcs, formatOf(name))
+
+	if docsErr != nil {
+		return nil, docsErr
+	}
+
+	if len(docs) != 1 || len(docs[0].Content) != 1 {
+		return nil, fmt.Errorf("%w: exactly one document expected", ErrInvalidInclude)
+	}
+
+	if err := in.resolve(docs[0], filepath.Dir(name)); err != nil {
+		return nil, err
+	}
+
+	result := docs[0].Content[0]
+	in.m.markSource(result, name)
+
+	return result, nil
+}
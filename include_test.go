@@ -0,0 +1,81 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/AlphaOne1/templig"
+)
+
+func TestInclude(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.yaml":     {Data: []byte("id: 1\nname: base\nconn: !include parts/conn.yaml\n")},
+		"parts/conn.yaml": {Data: []byte("url: {{ \"https://db\" | lower }}\npasses: !include ../passes.json\n")},
+		"passes.json":     {Data: []byte(`["pass0", "pass1"]`)},
+		"overlay.yaml":    {Data: []byte("conn: !include parts/conn.yaml\n")},
+	}
+
+	c, loadErr := templig.FromFS[TestConfig](fsys, "config.yaml")
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration with includes: %v", loadErr)
+	}
+
+	if c.Get().Conn == nil ||
+		c.Get().Conn.URL != "https://db" ||
+		!slices.Equal(c.Get().Conn.Passes, []string{"pass0", "pass1"}) {
+		t.Errorf("unexpected configuration: %+v", c.Get())
+	}
+
+	if p, _ := c.Explain("conn.url"); p.Source != "parts/conn.yaml" || p.Line != 1 || p.Layer != 0 {
+		t.Errorf("unexpected provenance of included value: %v", p)
+	}
+
+	if p, _ := c.Explain("conn.passes.1"); p.Source != "passes.json" {
+		t.Errorf("unexpected provenance of nested included value: %v", p)
+	}
+
+	if p, _ := c.Explain("name"); p.Source != "config.yaml" {
+		t.Errorf("unexpected provenance of value next to include: %v", p)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"self.yaml":    {Data: []byte("name: !include self.yaml\n")},
+		"a.yaml":       {Data: []byte("conn: !include b.yaml\n")},
+		"b.yaml":       {Data: []byte("url: !include a.yaml\n")},
+		"mapping.yaml": {Data: []byte("conn: !include {url: a}\n")},
+		"missing.yaml": {Data: []byte("conn: !include notThere.yaml\n")},
+		"multi.yaml":   {Data: []byte("conn: !include docs.yaml\n")},
+		"docs.yaml":    {Data: []byte("url: a\n---\nurl: b\n")},
+	}
+
+	tests := []struct {
+		file    string
+		wantErr error
+	}{
+		{file: "self.yaml", wantErr: templig.ErrIncludeCycle},
+		{file: "a.yaml", wantErr: templig.ErrIncludeCycle},
+		{file: "mapping.yaml", wantErr: templig.ErrInvalidInclude},
+		{file: "missing.yaml"},
+		{file: "multi.yaml", wantErr: templig.ErrInvalidInclude},
+	}
+
+	for _, test := range tests {
+		_, loadErr := templig.FromFS[TestConfig](fsys, test.file)
+
+		if loadErr == nil {
+			t.Errorf("%v: expected error including file", test.file)
+		}
+
+		if test.wantErr != nil && !errors.Is(loadErr, test.wantErr) {
+			t.Errorf("%v: expected error %v but got %v", test.file, test.wantErr, loadErr)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AlphaOne1/templig"
+)
+
+type TestKindsTLS struct {
+	Cert string `yaml:"cert"`
+}
+
+type TestKindsConfig struct {
+	Name string       `yaml:"name"`
+	TLS  TestKindsTLS `yaml:"tls"`
+}
+
+const (
+	testKindsBase    = "name: base\ntls: false\n"
+	testKindsOverlay = "tls:\n  cert: server.pem\n"
+)
+
+func TestKindMismatchError(t *testing.T) {
+	_, loadErr := templig.Load[TestKindsConfig](
+		templig.WithReaders(strings.NewReader(testKindsBase), strings.NewReader(testKindsOverlay)),
+	)
+
+	if !errors.Is(loadErr, templig.ErrNodeKindMismatch) {
+		t.Fatalf("expected error %v but got %v", templig.ErrNodeKindMismatch, loadErr)
+	}
+
+	for _, expected := range []string{"at tls", "scalar in reader 0, line 2, column 6", "mapping in reader 1, line 2"} {
+		if !strings.Contains(loadErr.Error(), expected) {
+			t.Errorf("expected error to contain %q, got %v", expected, loadErr)
+		}
+	}
+}
+
+func TestKindMismatchOverride(t *testing.T) {
+	c, loadErr := templig.Load[TestKindsConfig](
+		templig.WithReaders(strings.NewReader(testKindsBase), strings.NewReader(testKindsOverlay)),
+		templig.WithKindOverride(),
+	)
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration with kind override: %v", loadErr)
+	}
+
+	if c.Get().Name != "base" || c.Get().TLS.Cert != "server.pem" {
+		t.Errorf("unexpected configuration: %+v", c.Get())
+	}
+
+	warnings := c.Warnings()
+
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "tls changed from scalar in reader 0") {
+		t.Errorf("expected warning of the kind change, got %v", warnings)
+	}
+
+	if p, _ := c.Explain("tls"); p.Source != "reader 1" || len(p.Overridden) != 1 {
+		t.Errorf("unexpected provenance of overriding value: %v", p)
+	}
+}
+
+func TestKindMismatchNoWarnings(t *testing.T) {
+	c, loadErr := templig.Load[TestKindsConfig](
+		templig.WithReaders(strings.NewReader(testKindsOverlay), strings.NewReader(testKindsOverlay)),
+		templig.WithKindOverride(),
+	)
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration: %v", loadErr)
+	}
+
+	if len(c.Warnings()) != 0 {
+		t.Errorf("expected no warnings, got %v", c.Warnings())
+	}
+}
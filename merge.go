@@ -3,6 +3,9 @@ package templig
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -28,19 +31,177 @@ var (
 
 	// ErrUnequalNameAnchors is an error returned when operations on named anchors fail
 	// due to unequal anchor definitions.
+	//
+	// Deprecated: Differing anchors are merged now, keeping the anchor of the base. This error is not returned anymore.
 	ErrUnequalNameAnchors = errors.New("unequal named anchors not yet supported")
+
+	// ErrDeleteTargetMissing is an error returned in strict mode, when a deletion marker of an overlay targets a path
+	// that does not exist in the configuration below.
+	ErrDeleteTargetMissing = errors.New("deletion target does not exist")
 )
 
+// merger holds the state of merge operations, that is the origin of all nodes taking part in the merge.
+type merger struct {
+	layers  []string
+	origins map[*yaml.Node]*nodeOrigin
+
+	// weakLayers are the layers whose sequences are replaced by the following layers instead of being extended
+	weakLayers map[int]bool
+
+	strategy       SequenceStrategy
+	pathStrategies map[string]SequenceStrategy
+	nodeStrategies map[*yaml.Node]SequenceStrategy
+
+	// path is the path of the nodes currently merged
+	path []string
+
+	// strict lets deletion markers without target fail
+	strict bool
+
+	// kindOverride lets values of a different kind replace the values below instead of failing
+	kindOverride bool
+	warnings     []string
+
+	// replaced maps anchored nodes to the nodes replacing them in the merge result
+	replaced map[*yaml.Node]*yaml.Node
+
+	// included maps nodes read from included files to the names of these files
+	included map[*yaml.Node]string
+
+	// key decrypts the encrypted values of the layers
+	key []byte
+}
+
+// nodeOrigin records the layer a node was read from and the definitions it replaced while merging.
+type nodeOrigin struct {
+	layer      int
+	overridden []Origin
+
+	// source is the name of the included file the node was read from, if it differs from the layer
+	source string
+}
+
 // MergeYAMLNodes merges the content of node `b` into node `a`.
 // If `a` contains already an element with the same name and of the same kind as `b`,
-// they are merged recursively.
+// they are merged recursively. Merged nodes keep the anchor of `a`, or if it has none, the one of `b`. Aliases of both
+// refer to the merged node afterward. Anchors of the same name used for different nodes are made unique.
 func MergeYAMLNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
+	return new(merger).mergeRoot(nodeA, nodeB)
+}
+
+// addLayer registers a new layer with the given name and marks all nodes of the given node structure to belong to it.
+func (m *merger) addLayer(name string, node *yaml.Node) {
+	m.layers = append(m.layers, name)
+	m.markLayer(node, len(m.layers)-1)
+}
+
+// markLayer marks the given node and all its children to belong to the given layer.
+func (m *merger) markLayer(node *yaml.Node, layer int) {
+	if m.origins == nil {
+		m.origins = make(map[*yaml.Node]*nodeOrigin)
+	}
+
+	m.origins[node] = &nodeOrigin{layer: layer, source: m.included[node]}
+
+	for _, v := range node.Content {
+		m.markLayer(v, layer)
+	}
+}
+
+// markSource marks the given node and all its children to be read from the included file with the given name. Nodes
+// already marked by nested includes keep their source.
+func (m *merger) markSource(node *yaml.Node, name string) {
+	if m.included == nil {
+		m.included = make(map[*yaml.Node]string)
+	}
+
+	if _, found := m.included[node]; found {
+		return
+	}
+
+	m.included[node] = name
+
+	for _, c := range node.Content {
+		m.markSource(c, name)
+	}
+}
+
+// origin gives the origin of the given node.
+func (m *merger) origin(node *yaml.Node) Origin {
+	result := Origin{
+		Layer:  -1,
+		Line:   node.Line,
+		Column: node.Column,
+	}
+
+	if o, found := m.origins[node]; found && o.layer < len(m.layers) {
+		result.Layer = o.layer
+		result.Source = m.layers[o.layer]
+
+		if len(o.source) > 0 {
+			result.Source = o.source
+		}
+	}
+
+	return result
+}
+
+// sourceName gives the name of the source the given node was read from.
+func (m *merger) sourceName(node *yaml.Node) string {
+	if o := m.origin(node); o.Layer >= 0 {
+		return o.Source
+	}
+
+	return "unknown source"
+}
+
+// copyNode creates a shallow copy of the given node, that retains the origin of the original.
+func (m *merger) copyNode(node *yaml.Node) *yaml.Node {
+	ret := *node
+
+	if o, found := m.origins[node]; found {
+		m.origins[&ret] = o
+	}
+
+	return &ret
+}
+
+// override creates a shallow copy of the node `b` replacing the node `a`. The origin of `a` is recorded as overridden.
+func (m *merger) override(a, b *yaml.Node) *yaml.Node {
+	ret := *b
+
+	if o, found := m.origins[b]; found {
+		overridden := []Origin{m.origin(a)}
+
+		if prev, prevFound := m.origins[a]; prevFound {
+			overridden = append(append([]Origin{}, prev.overridden...), overridden...)
+		}
+
+		m.origins[&ret] = &nodeOrigin{
+			layer:      o.layer,
+			overridden: overridden,
+			source:     o.source,
+		}
+	}
+
+	return &ret
+}
+
+// merge merges the content of node `b` into node `a`, see [MergeYAMLNodes].
+func (m *merger) merge(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
 	if nodeA == nil || nodeB == nil {
 		return nil, ErrNodeNil
 	}
 
+	// anchors of nodes reached by aliases belong to the aliased node, not to the merged one
+	anchorB := nodeB
+
+	for nodeB.Kind == yaml.AliasNode && nodeA.Kind != yaml.AliasNode {
+		nodeB = nodeB.Alias
+	}
+
 	if nodeA.Kind != nodeB.Kind && nodeA.Kind != yaml.AliasNode && nodeB.Kind != yaml.AliasNode {
-		return nil, ErrNodeKindMismatch
+		return m.mergeKindMismatch(nodeA, nodeB)
 	}
 
 	for nodeB.Kind == yaml.AliasNode {
@@ -52,35 +213,90 @@ func MergeYAMLNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
 
 	switch nodeA.Kind {
 	case yaml.DocumentNode:
-		res, resErr = mergeDocumentNodes(nodeA, nodeB)
+		res, resErr = m.mergeDocumentNodes(nodeA, nodeB)
 	case yaml.SequenceNode:
-		res, resErr = mergeSequenceNodes(nodeA, nodeB)
+		res, resErr = m.mergeSequenceNodes(nodeA, nodeB)
 	case yaml.MappingNode:
-		res, resErr = mergeMappingNodes(nodeA, nodeB)
+		res, resErr = m.mergeMappingNodes(nodeA, nodeB)
 	case yaml.ScalarNode:
-		res, resErr = mergeScalarNodes(nodeA, nodeB)
+		res, resErr = m.mergeScalarNodes(nodeA, nodeB)
 	case yaml.AliasNode:
-		res, resErr = mergeAliasNodes(nodeA, nodeB)
+		res, resErr = m.mergeAliasNodes(nodeA, nodeB)
 	default:
 		resErr = fmt.Errorf("unhandled node type %v: %w", nodeA.Kind, ErrNodeTypeUnhandled)
 	}
 
-	if len(nodeA.Anchor) > 0 {
-		if len(nodeB.Anchor) > 0 && nodeA.Anchor != nodeB.Anchor {
-			res = nil
-			resErr = fmt.Errorf("%w (source %v, merge %v)",
-				ErrUnequalNameAnchors,
-				nodeA.Anchor,
-				nodeB.Anchor)
-		}
-	} else {
-		nodeA.Anchor = nodeB.Anchor
+	if res != nil {
+		m.mergeAnchor(nodeA, anchorB, res)
 	}
 
 	return res, resErr
 }
 
-func mergeAliasNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
+// mergeRoot merges the node `b` into the node `a` like merge, afterward fixing the anchors and aliases of the result,
+// so that aliases of both nodes refer to the merged nodes.
+func (m *merger) mergeRoot(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
+	res, resErr := m.merge(nodeA, nodeB)
+
+	if resErr != nil {
+		return nil, resErr
+	}
+
+	m.fixAnchors(res)
+
+	return res, nil
+}
+
+// mergeKindMismatch handles nodes of different kinds. If overriding is enabled, the node `b` replaces the node `a`
+// and a warning is recorded, otherwise an error giving the path and the positions of both nodes is returned.
+func (m *merger) mergeKindMismatch(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
+	path := strings.Join(m.path, ".")
+
+	if len(path) == 0 {
+		path = "root"
+	}
+
+	if !m.kindOverride || nodeA.Kind == yaml.DocumentNode || nodeB.Kind == yaml.DocumentNode {
+		return nil, fmt.Errorf("%w at %v: %v in %v, overlaid by %v in %v",
+			ErrNodeKindMismatch, path, kindName(nodeA.Kind), m.position(nodeA), kindName(nodeB.Kind), m.position(nodeB))
+	}
+
+	cleaned, cleanErr := m.dropDeleteMarkers(nodeB)
+
+	if cleanErr != nil {
+		return nil, cleanErr
+	}
+
+	m.warnings = append(m.warnings, fmt.Sprintf("%v changed from %v in %v to %v in %v",
+		path, kindName(nodeA.Kind), m.position(nodeA), kindName(nodeB.Kind), m.position(nodeB)))
+
+	return m.override(nodeA, cleaned), nil
+}
+
+// position gives the source position of the given node for messages.
+func (m *merger) position(node *yaml.Node) string {
+	return fmt.Sprintf("%v, line %v, column %v", m.sourceName(node), node.Line, node.Column)
+}
+
+// kindName gives a human-readable name of the given node kind.
+func kindName(kind yaml.Kind) string {
+	switch kind {
+	case yaml.DocumentNode:
+		return "document"
+	case yaml.SequenceNode:
+		return "sequence"
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.ScalarNode:
+		return "scalar"
+	case yaml.AliasNode:
+		return "alias"
+	default:
+		return "unknown kind " + strconv.Itoa(int(kind))
+	}
+}
+
+func (m *merger) mergeAliasNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
 	if nodeA == nil || nodeB == nil {
 		return nil, ErrNodeNil
 	}
@@ -89,13 +305,13 @@ func mergeAliasNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
 		return nil, ErrAliasNodeExpected
 	}
 
-	tmp := *nodeA.Alias
+	tmp := m.copyNode(nodeA.Alias)
 	tmp.Anchor = ""
 
-	return MergeYAMLNodes(&tmp, nodeB)
+	return m.merge(tmp, nodeB)
 }
 
-func mergeDocumentNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
+func (m *merger) mergeDocumentNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
 	if nodeA == nil || nodeB == nil {
 		return nil, ErrNodeNil
 	}
@@ -104,12 +320,12 @@ func mergeDocumentNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
 		return nil, ErrNodeKindMismatch
 	}
 
-	// this is the top level, the YAML library does not support multiple documents in one file
+	// this is the top level, documents of multi-document sources are overlaid one by one
 	if len(nodeA.Content) == 1 && len(nodeB.Content) == 1 {
-		ret := *nodeA
+		ret := m.copyNode(nodeA)
 		ret.Content = make([]*yaml.Node, 1)
 
-		merged, mergedErr := MergeYAMLNodes(nodeA.Content[0], nodeB.Content[0])
+		merged, mergedErr := m.merge(nodeA.Content[0], nodeB.Content[0])
 
 		if mergedErr != nil {
 			return nil, mergedErr
@@ -117,13 +333,13 @@ func mergeDocumentNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
 
 		ret.Content[0] = merged
 
-		return &ret, nil
+		return ret, nil
 	}
 
 	return nil, ErrUnexpectedDocumentNodeConfiguration
 }
 
-func mergeScalarNodes(a, b *yaml.Node) (*yaml.Node, error) {
+func (m *merger) mergeScalarNodes(a, b *yaml.Node) (*yaml.Node, error) {
 	if a == nil || b == nil {
 		return nil, ErrNodeNil
 	}
@@ -132,12 +348,10 @@ func mergeScalarNodes(a, b *yaml.Node) (*yaml.Node, error) {
 		return nil, ErrNodeKindMismatch
 	}
 
-	ret := *b
-
-	return &ret, nil
+	return m.override(a, b), nil
 }
 
-func mergeSequenceNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
+func (m *merger) mergeSequenceNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
 	if nodeA == nil || nodeB == nil {
 		return nil, ErrNodeNil
 	}
@@ -146,15 +360,25 @@ func mergeSequenceNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
 		return nil, ErrNodeKindMismatch
 	}
 
-	ret := *nodeA
-	ret.Content = make([]*yaml.Node, 0, len(nodeA.Content)+len(nodeB.Content))
-	ret.Content = append(ret.Content, nodeA.Content...)
-	ret.Content = append(ret.Content, nodeB.Content...)
+	strategy := m.sequenceStrategy(nodeA, nodeB)
+
+	if strategy == SequenceReplace {
+		ret := m.override(nodeA, nodeB)
+		ret.Tag = "!!seq"
+
+		return ret, nil
+	}
+
+	ret := m.copyNode(nodeA)
 
-	return &ret, nil
+	if err := m.mergeSequenceItems(ret, nodeA, nodeB, strategy); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
 }
 
-func mergeAddValue(node, key, value *yaml.Node) error {
+func (m *merger) mergeAddValue(node, key, value *yaml.Node) error {
 	var keyIndex int
 	var valueIndex int
 
@@ -166,7 +390,15 @@ func mergeAddValue(node, key, value *yaml.Node) error {
 			key.Kind == yaml.ScalarNode &&
 			node.Content[keyIndex].Value == key.Value {
 
-			merged, mergedErr := MergeYAMLNodes(node.Content[valueIndex], value)
+			if isDeleteMarker(value) {
+				node.Content = slices.Delete(node.Content, keyIndex, valueIndex+1)
+
+				return nil
+			}
+
+			m.pushPath(key.Value)
+			merged, mergedErr := m.merge(node.Content[valueIndex], value)
+			m.popPath()
 
 			if mergedErr == nil {
 				node.Content[valueIndex] = merged
@@ -176,12 +408,100 @@ func mergeAddValue(node, key, value *yaml.Node) error {
 		}
 	}
 
-	node.Content = append(node.Content, key, value)
+	if isDeleteMarker(value) {
+		return m.missingDeleteTarget(key, value)
+	}
+
+	cleaned, cleanErr := m.dropDeleteMarkers(value)
+
+	if cleanErr != nil {
+		return cleanErr
+	}
+
+	node.Content = append(node.Content, key, cleaned)
 
 	return nil
 }
 
-func mergeMappingNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
+// isDeleteMarker checks if the given node marks its key for deletion using the `!delete` or `!unset` tag.
+func isDeleteMarker(node *yaml.Node) bool {
+	return node.Tag == "!delete" || node.Tag == "!unset"
+}
+
+// missingDeleteTarget reports the deletion marker of the given key, that has no target. Outside strict mode, such
+// markers are ignored.
+func (m *merger) missingDeleteTarget(key, value *yaml.Node) error {
+	if !m.strict {
+		return nil
+	}
+
+	m.pushPath(key.Value)
+	defer m.popPath()
+
+	o := m.origin(value)
+
+	return fmt.Errorf("%w: %v in %v, line %v, column %v",
+		ErrDeleteTargetMissing, strings.Join(m.path, "."), o.Source, o.Line, o.Column)
+}
+
+// dropDeleteMarkers gives the given node structure without the keys marked for deletion. As there is nothing to
+// delete in a new node structure, these are missing targets.
+func (m *merger) dropDeleteMarkers(node *yaml.Node) (*yaml.Node, error) {
+	switch node.Kind { //nolint:exhaustive
+	case yaml.DocumentNode:
+		for i, c := range node.Content {
+			cleaned, err := m.dropDeleteMarkers(c)
+
+			if err != nil {
+				return nil, err
+			}
+
+			node.Content[i] = cleaned
+		}
+	case yaml.SequenceNode:
+		for i, c := range node.Content {
+			m.pushPath(strconv.Itoa(i))
+			cleaned, err := m.dropDeleteMarkers(c)
+			m.popPath()
+
+			if err != nil {
+				return nil, err
+			}
+
+			node.Content[i] = cleaned
+		}
+	case yaml.MappingNode:
+		content := make([]*yaml.Node, 0, len(node.Content))
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			if isDeleteMarker(value) {
+				if err := m.missingDeleteTarget(key, value); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			m.pushPath(key.Value)
+			cleaned, err := m.dropDeleteMarkers(value)
+			m.popPath()
+
+			if err != nil {
+				return nil, err
+			}
+
+			content = append(content, key, cleaned)
+		}
+
+		node.Content = content
+	}
+
+	return node, nil
+}
+
+func (m *merger) mergeMappingNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
 	if nodeA == nil || nodeB == nil {
 		return nil, ErrNodeNil
 	}
@@ -193,7 +513,7 @@ func mergeMappingNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
 	var keyNode *yaml.Node
 	var valueNode *yaml.Node
 
-	ret := *nodeA
+	ret := m.copyNode(nodeA)
 	ret.Content = make([]*yaml.Node, 0, len(nodeA.Content)+len(nodeB.Content))
 	ret.Content = append(ret.Content, nodeA.Content...)
 
@@ -201,10 +521,10 @@ func mergeMappingNodes(nodeA, nodeB *yaml.Node) (*yaml.Node, error) {
 		keyNode = nodeB.Content[i]
 		valueNode = nodeB.Content[i+1]
 
-		if mergeErr := mergeAddValue(&ret, keyNode, valueNode); mergeErr != nil {
+		if mergeErr := m.mergeAddValue(ret, keyNode, valueNode); mergeErr != nil {
 			return nil, mergeErr
 		}
 	}
 
-	return &ret, nil
+	return ret, nil
 }
@@ -7,13 +7,15 @@ import (
 )
 
 func TestNullArgs(t *testing.T) {
+	m := new(merger)
 	mergeFuncs := []func(*yaml.Node, *yaml.Node) (*yaml.Node, error){
 		MergeYAMLNodes,
-		mergeAliasNodes,
-		mergeDocumentNodes,
-		mergeMappingNodes,
-		mergeScalarNodes,
-		mergeSequenceNodes,
+		m.merge,
+		m.mergeAliasNodes,
+		m.mergeDocumentNodes,
+		m.mergeMappingNodes,
+		m.mergeScalarNodes,
+		m.mergeSequenceNodes,
 	}
 
 	for k, v := range mergeFuncs {
@@ -30,13 +32,15 @@ func TestNullArgs(t *testing.T) {
 }
 
 func TestMismatchArgs(t *testing.T) {
+	m := new(merger)
 	mergeFuncs := []func(*yaml.Node, *yaml.Node) (*yaml.Node, error){
 		MergeYAMLNodes,
-		mergeAliasNodes,
-		mergeDocumentNodes,
-		mergeMappingNodes,
-		mergeScalarNodes,
-		mergeSequenceNodes,
+		m.merge,
+		m.mergeAliasNodes,
+		m.mergeDocumentNodes,
+		m.mergeMappingNodes,
+		m.mergeScalarNodes,
+		m.mergeSequenceNodes,
 	}
 
 	a := yaml.Node{
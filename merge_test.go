@@ -2,6 +2,7 @@ package templig_test
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -107,8 +108,59 @@ a: &ref0
 			b: `
 a: &ref1
     a: 3`,
-			want:    ``,
-			wantErr: true,
+			want: `a: &ref0
+    a: 3`,
+			wantErr: false,
+		},
+		{ // 9
+			name: "anchor mismatch with aliases",
+			a: `
+a: &ref0
+    a: 3
+b: *ref0`,
+			b: `
+a: &ref1
+    c: 4
+d: *ref1`,
+			want: `a: &ref0
+    a: 3
+    c: 4
+b: *ref0
+d: *ref0`,
+			wantErr: false,
+		},
+		{ // 10
+			name: "anchor name reused",
+			a: `
+a: &ref
+    x: 1
+b: *ref`,
+			b: `
+c: &ref
+    y: 2
+d: *ref`,
+			want: `a: &ref
+    x: 1
+b: *ref
+c: &ref_1
+    y: 2
+d: *ref_1`,
+			wantErr: false,
+		},
+		{ // 11
+			name: "anchor added by overlay",
+			a: `
+a:
+    x: 1`,
+			b: `
+a: &ref
+    y: 2
+b: *ref`,
+			want: `a: &ref
+    x: 1
+    y: 2
+b: *ref`,
+			wantErr: false,
 		},
 	}
 
@@ -202,3 +254,33 @@ func TestUnknownNodeKind(t *testing.T) {
 		t.Errorf("expected an error merging unknown node kind")
 	}
 }
+
+func TestMergeAliasOfDeletedAnchor(t *testing.T) {
+	c, loadErr := templig.From[map[string]map[string]int](
+		strings.NewReader("a: &ref\n  x: 1\nb: *ref\n"),
+		strings.NewReader("a: !delete\nb:\n  y: 2\n"),
+	)
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration: %v", loadErr)
+	}
+
+	if _, found := (*c.Get())["a"]; found || (*c.Get())["b"]["x"] != 1 || (*c.Get())["b"]["y"] != 2 {
+		t.Errorf("unexpected configuration: %v", *c.Get())
+	}
+}
+
+func TestMergeAliasDecoded(t *testing.T) {
+	c, loadErr := templig.From[map[string]map[string]int](
+		strings.NewReader("a: &ref\n  x: 1\nb: *ref\n"),
+		strings.NewReader("a:\n  x: 2\n"),
+	)
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration: %v", loadErr)
+	}
+
+	if (*c.Get())["b"]["x"] != 2 {
+		t.Errorf("expected alias to refer to the merged value, got %v", *c.Get())
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"text/template"
+	"time"
+)
+
+// Option is a functional option to configure the loading of a configuration using [Load].
+type Option func(*options)
+
+// options holds the settings of a single loading process. In contrast to the package level variables like
+// [TemplateFunctions] and [SecretRE], these settings are local to one call of [Load], so that different parts of a
+// program can load their configurations with different settings.
+type options struct {
+	sources  []source
+	funcs    template.FuncMap
+	secretRE *regexp.Regexp
+	strict   bool
+	files    []string
+	poll     time.Duration
+	fsys     fs.FS
+	ctx      context.Context //nolint:containedctx
+	format   Format
+	env      *envOptions
+	flags    *flag.FlagSet
+
+	sequences     SequenceStrategy
+	pathSequences map[string]SequenceStrategy
+	kindOverride  bool
+	selector      *documentSelector
+	patches       []patch
+
+	profiles         []string
+	requiredProfiles []string
+	profilesFromEnv  bool
+
+	decryptionKey []byte
+	keyFile       string
+
+	// globs are the patterns of the expanded directory and glob sources
+	globs []dependencyGlob
+}
+
+// source is a single configuration input, e.g. a file or an io.Reader.
+type source struct {
+	name   string
+	path   string
+	format Format
+	open   func() (io.ReadCloser, error)
+
+	// glob is the pattern of the files of directory and glob sources, that are expanded when loading
+	glob       string
+	configOnly bool
+}
+
+// newOptions creates the options resulting of the application of the given functional options.
+func newOptions(opts ...Option) *options {
+	result := options{
+		funcs: templigFunctions(),
+		poll:  defaultPollInterval,
+		ctx:   context.Background(),
+	}
+
+	replaceFunc(result.funcs, "read", readFile, result.readFile)
+	replaceFunc(result.funcs, "context", contextValue, result.contextValue)
+	replaceFunc(result.funcs, "secret", lookupSecret, result.lookupSecret)
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&result)
+		}
+	}
+
+	return &result
+}
+
+// WithFiles adds the given configuration files to the configuration sources. The first source is considered the
+// base, all following ones are loaded on top of it using the [MergeYAMLNodes] functionality.
+func WithFiles(paths ...string) Option {
+	return func(o *options) {
+		for _, path := range paths {
+			o.sources = append(o.sources, o.fileSource(path))
+		}
+	}
+}
+
+// fileSource creates a source reading the file with the given path.
+func (o *options) fileSource(path string) source {
+	return source{
+		name:   path,
+		path:   path,
+		format: formatOf(path),
+		open: func() (io.ReadCloser, error) {
+			return o.openFile(path)
+		},
+	}
+}
+
+// WithReaders adds the given io.Reader to the configuration sources. The first source is considered the
+// base, all following ones are loaded on top of it using the [MergeYAMLNodes] functionality.
+func WithReaders(readers ...io.Reader) Option {
+	return func(o *options) {
+		for _, r := range readers {
+			o.sources = append(o.sources, readerSource(fmt.Sprintf("reader %d", len(o.sources)), r))
+		}
+	}
+}
+
+// readerSource creates a YAML source of the given name reading from the io.Reader.
+func readerSource(name string, r io.Reader) source {
+	return source{
+		name:   name,
+		format: FormatYAML,
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(r), nil
+		},
+	}
+}
+
+// read reads the content of the source. Reading is aborted, when the given context is done.
+func (s source) read(ctx context.Context) ([]byte, error) {
+	return withContext(ctx, func() ([]byte, error) {
+		r, openErr := s.open()
+
+		if openErr != nil {
+			return nil, openErr
+		}
+
+		defer func() { _ = r.Close() }()
+
+		return io.ReadAll(r)
+	})
+}
+
+// WithFuncs adds the given template functions to the ones available in the configuration templates. Functions with
+// the same name as an already present function replace the latter. As with [TemplateFunctions], please use the
+// prefix `uP` for user-provided functions.
+func WithFuncs(funcs template.FuncMap) Option {
+	return func(o *options) {
+		maps.Insert(o.funcs, maps.All(funcs))
+	}
+}
+
+// WithSecretPattern sets the regular expression used to identify secret values when the configuration is written
+// using the secret hiding functions, e.g. [Config.ToSecretsHidden]. If not set, [SecretRE] is used.
+func WithSecretPattern(re *regexp.Regexp) Option {
+	return func(o *options) {
+		o.secretRE = re
+	}
+}
+
+// WithStrict enables the strict decoding mode. In strict mode, every key of the configuration that has no
+// corresponding field in the configuration type is reported as an error giving its source, line and full path.
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithKindOverride lets values of overlays replace the values below, if they are of a different kind, e.g. a mapping
+// replacing a scalar. By default, such overlays fail with [ErrNodeKindMismatch]. The replacements are reported by
+// [Config.Warnings].
+func WithKindOverride() Option {
+	return func(o *options) {
+		o.kindOverride = true
+	}
+}
+
+// WithFS resolves all configuration files, files read using the `read` template function and files of the `file`
+// secret provider against the given file system. This allows e.g. to use configurations from an embed.FS. As usual for
+// fs.FS, paths are slash separated and unrooted. Changes of files in a file system given here are not detected by
+// [WatchLoad].
+func WithFS(fsys fs.FS) Option {
+	return func(o *options) {
+		o.fsys = fsys
+	}
+}
+
+// WithFormat sets the format of all configuration sources. By default, the format of files is detected using their
+// extension (see [Format]), readers are expected to give YAML.
+func WithFormat(format Format) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithContext sets the context of the loading process. The loading is aborted, when the context is done. The context
+// is also available to the template functions: `read` stops waiting for a file, when the context is done, and
+// user-provided functions can receive it as argument using the template function `context`, e.g.
+// `{{ uPFetch context "name" }}`.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithPollInterval sets the interval in which [Watch] checks the configuration files for changes, if no native file
+// change notification is available.
+func WithPollInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.poll = interval
+	}
+}
+
+// readFile is the variant of the template function `read` used during loading. It records the files read, so they can
+// be watched for changes.
+func (o *options) readFile(fileName string) (any, error) {
+	o.files = append(o.files, fileName)
+
+	return withContext(o.ctx, func() (any, error) {
+		return readFileWith(o.openFile, fileName)
+	})
+}
+
+// contextValue is the variant of the template function `context` used during loading. It gives the context of the
+// loading process.
+func (o *options) contextValue() context.Context {
+	return o.ctx
+}
+
+// lookupSecret is the variant of the template function `secret` used during loading. The secret providers get the
+// context of the loading process. Secret files are read from the file system given by [WithFS] and recorded, so they
+// can be watched for changes.
+func (o *options) lookupSecret(reference string) (string, error) {
+	return lookupSecretWith(o.ctx, reference, func(name, path string) (SecretProvider, bool) {
+		provider, found := secretProvider(name)
+		fileProvider, isFile := provider.(FileSecretProvider)
+
+		if !isFile {
+			return provider, found
+		}
+
+		o.files = append(o.files, fileProvider.file(path))
+
+		return SecretProviderFunc(func(_ context.Context, path string) ([]byte, error) {
+			return fileProvider.read(o.openFile, path)
+		}), true
+	})
+}
+
+// openFile opens the file with the given name, either from the file system given by [WithFS] or the operating system.
+func (o *options) openFile(name string) (io.ReadCloser, error) {
+	if o.fsys != nil {
+		f, err := o.fsys.Open(path.Clean(filepath.ToSlash(name)))
+
+		return f, wrapError("could not open "+name+": %w", err)
+	}
+
+	f, err := os.Open(filepath.Clean(name))
+
+	return f, wrapError("could not open "+name+": %w", err)
+}
+
+// dependencies gives all the files the loading process depends on.
+func (o *options) dependencies() []string {
+	if o.fsys != nil {
+		// files of other file systems cannot be watched
+		return nil
+	}
+
+	result := make([]string, 0, len(o.sources)+len(o.files))
+
+	for _, src := range o.sources {
+		if len(src.path) > 0 {
+			result = append(result, src.path)
+		}
+	}
+
+	return append(result, o.files...)
+}
+
+// dependencyGlobs gives the patterns of the files the loading process depends on, that may be created later.
+func (o *options) dependencyGlobs() []dependencyGlob {
+	if o.fsys != nil {
+		// files of other file systems cannot be watched
+		return nil
+	}
+
+	return o.globs
+}
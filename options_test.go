@@ -0,0 +1,300 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+	"time"
+
+	"github.com/AlphaOne1/templig"
+)
+
+func TestLoadNoSources(t *testing.T) {
+	c, loadErr := templig.Load[TestConfig]()
+
+	if !errors.Is(loadErr, templig.ErrNoConfigSources) {
+		t.Errorf("expected error %v but got %v", templig.ErrNoConfigSources, loadErr)
+	}
+
+	if c != nil {
+		t.Errorf("loading without sources should have returned nil")
+	}
+}
+
+func TestLoadMixedSources(t *testing.T) {
+	c, loadErr := templig.Load[TestConfig](
+		templig.WithFiles("testData/test_config_0.yaml"),
+		templig.WithReaders(strings.NewReader("name: Overlaid")),
+	)
+
+	if loadErr != nil {
+		t.Fatalf("no error expected loading mixed sources: %v", loadErr)
+	}
+
+	if c.Get().ID != 9 || c.Get().Name != "Overlaid" {
+		t.Errorf("unexpected configuration content: %+v", c.Get())
+	}
+}
+
+func TestLoadWithFuncs(t *testing.T) {
+	funcs0 := templig.WithFuncs(template.FuncMap{
+		"uPName": func() string { return "Name0" },
+	})
+	funcs1 := templig.WithFuncs(template.FuncMap{
+		"uPName": func() string { return "Name1" },
+	})
+
+	c0, err0 := templig.Load[TestConfig](templig.WithReaders(strings.NewReader(`name: {{ uPName }}`)), funcs0)
+	c1, err1 := templig.Load[TestConfig](templig.WithReaders(strings.NewReader(`name: {{ uPName }}`)), funcs1)
+
+	if err0 != nil || err1 != nil {
+		t.Fatalf("no error expected using custom functions: %v", errors.Join(err0, err1))
+	}
+
+	if c0.Get().Name != "Name0" || c1.Get().Name != "Name1" {
+		t.Errorf("functions of different loads interfere, got %v and %v", c0.Get().Name, c1.Get().Name)
+	}
+
+	if _, err := templig.Load[TestConfig](templig.WithReaders(strings.NewReader(`name: {{ uPName }}`))); err == nil {
+		t.Errorf("expected error using function not given to the load")
+	}
+}
+
+func TestLoadWithSecretPattern(t *testing.T) {
+	c, loadErr := templig.Load[TestConfig](
+		templig.WithFiles("testData/test_config_0.yaml"),
+		templig.WithSecretPattern(regexp.MustCompile("url")),
+	)
+
+	if loadErr != nil {
+		t.Fatalf("no error expected loading with secret pattern: %v", loadErr)
+	}
+
+	buf := bytes.Buffer{}
+
+	if err := c.ToSecretsHiddenStructured(&buf); err != nil {
+		t.Errorf("could not generate secrets-hidden config: %v", err)
+	}
+
+	if strings.Contains(buf.String(), "https://www.tests.to") {
+		t.Errorf("found secret url in secrets-hidden output:\n%v", buf.String())
+	}
+
+	if !strings.Contains(buf.String(), "pass0") {
+		t.Errorf("expected passes to be visible with custom secret pattern:\n%v", buf.String())
+	}
+}
+
+type TestConfigInline struct {
+	TestConfig `yaml:",inline"`
+
+	Extra map[string]int `yaml:"extra"`
+}
+
+func TestLoadStrict(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		inFiles   []string
+		wantPaths []string
+	}{
+		{ // 0
+			name:    "single known",
+			inFiles: []string{"testData/test_config_0.yaml"},
+		},
+		{ // 1
+			name:      "single unknown",
+			in:        "id: 1\nconn:\n  url: x\n  pasess: []\n",
+			wantPaths: []string{"conn.pasess in reader 0, line 4, column 3"},
+		},
+		{ // 2
+			name:    "overlay unknown",
+			inFiles: []string{"testData/test_config_0.yaml", "testData/test_config_0_overlay_unknown.yaml"},
+			wantPaths: []string{
+				"conn.pases in testData/test_config_0_overlay_unknown.yaml, line 3, column 5",
+				"nme in testData/test_config_0_overlay_unknown.yaml, line 5, column 1",
+			},
+		},
+	}
+
+	for testNum, test := range tests {
+		sources := func() templig.Option {
+			if len(test.in) > 0 {
+				return templig.WithReaders(strings.NewReader(test.in))
+			}
+
+			return templig.WithFiles(test.inFiles...)
+		}
+
+		_, loadErr := templig.Load[TestConfig](sources(), templig.WithStrict())
+
+		if len(test.wantPaths) == 0 && loadErr != nil {
+			t.Errorf("%v - %v: did not expect error but got %v", testNum, test.name, loadErr)
+		}
+
+		if len(test.wantPaths) > 0 && !errors.Is(loadErr, templig.ErrUnknownField) {
+			t.Errorf("%v - %v: expected unknown field error but got %v", testNum, test.name, loadErr)
+		}
+
+		for _, p := range test.wantPaths {
+			if loadErr == nil || !strings.Contains(loadErr.Error(), p) {
+				t.Errorf("%v - %v: expected error to mention %v, but got %v", testNum, test.name, p, loadErr)
+			}
+		}
+
+		if _, err := templig.Load[TestConfig](sources()); err != nil {
+			t.Errorf("%v - %v: non-strict loading should not fail: %v", testNum, test.name, err)
+		}
+	}
+}
+
+func TestLoadStrictInline(t *testing.T) {
+	c, loadErr := templig.Load[TestConfigInline](
+		templig.WithReaders(strings.NewReader("id: 1\nname: n\nextra:\n  a: 1\n  b: 2\n")),
+		templig.WithStrict(),
+	)
+
+	if loadErr != nil {
+		t.Fatalf("did not expect error loading inlined structure: %v", loadErr)
+	}
+
+	if c.Get().ID != 1 || c.Get().Extra["b"] != 2 {
+		t.Errorf("unexpected configuration content: %+v", c.Get())
+	}
+
+	_, loadErr = templig.Load[TestConfigInline](
+		templig.WithReaders(strings.NewReader("id: 1\nnam: n\n")),
+		templig.WithStrict(),
+	)
+
+	if !errors.Is(loadErr, templig.ErrUnknownField) {
+		t.Errorf("expected unknown field error but got %v", loadErr)
+	}
+}
+
+func TestFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config/base.yaml": &fstest.MapFile{
+			Data: []byte("id: 9\nname: {{ read \"secrets/name.txt\" | required \"name required\" | quote }}\n"),
+		},
+		"config/overlay.yaml": &fstest.MapFile{
+			Data: []byte("conn:\n  url: https://www.tests.to\n"),
+		},
+		"secrets/name.txt": &fstest.MapFile{
+			Data: []byte("Name0"),
+		},
+	}
+
+	c, fromErr := templig.FromFS[TestConfig](fsys, "config/base.yaml", "config/overlay.yaml")
+
+	if fromErr != nil {
+		t.Fatalf("no error expected loading from file system: %v", fromErr)
+	}
+
+	if c.Get().ID != 9 || c.Get().Name != "Name0" || c.Get().Conn == nil || c.Get().Conn.URL != "https://www.tests.to" {
+		t.Errorf("unexpected configuration content: %+v", c.Get())
+	}
+
+	if _, err := templig.FromFS[TestConfig](fsys, "testData/test_config_0.yaml"); err == nil {
+		t.Errorf("expected error loading file not contained in file system")
+	}
+
+	if _, err := templig.FromFS[TestConfig](fsys); !errors.Is(err, templig.ErrNoConfigPaths) {
+		t.Errorf("expected error %v but got %v", templig.ErrNoConfigPaths, err)
+	}
+
+	delete(fsys, "secrets/name.txt")
+
+	if _, err := templig.FromFS[TestConfig](fsys, "config/base.yaml"); err == nil {
+		t.Errorf("expected error reading file not contained in file system")
+	}
+}
+
+// blockingFS is a file system, whose files cannot be opened until it is released, simulating a hung network mount.
+type blockingFS struct {
+	release chan struct{}
+}
+
+func (b blockingFS) Open(name string) (fs.File, error) {
+	<-b.release
+
+	return nil, fs.ErrNotExist
+}
+
+func TestLoadContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := templig.FromContext[TestConfig](canceled, strings.NewReader("id: 9")); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error %v but got %v", context.Canceled, err)
+	}
+
+	_, fileErr := templig.FromFileContext[TestConfig](canceled, "testData/test_config_0.yaml")
+
+	if !errors.Is(fileErr, context.Canceled) {
+		t.Errorf("expected error %v but got %v", context.Canceled, fileErr)
+	}
+
+	if _, err := templig.FromContext[TestConfig](canceled); !errors.Is(err, templig.ErrNoConfigReaders) {
+		t.Errorf("expected error %v but got %v", templig.ErrNoConfigReaders, err)
+	}
+
+	if _, err := templig.FromFileContext[TestConfig](canceled); !errors.Is(err, templig.ErrNoConfigPaths) {
+		t.Errorf("expected error %v but got %v", templig.ErrNoConfigPaths, err)
+	}
+
+	c, err := templig.FromFileContext[TestConfig](context.Background(), "testData/test_config_0.yaml")
+
+	if err != nil || c.Get().ID != 9 {
+		t.Errorf("expected configuration to be loaded, but got error %v", err)
+	}
+}
+
+func TestLoadContextHungRead(t *testing.T) {
+	fsys := blockingFS{release: make(chan struct{})}
+	defer close(fsys.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := templig.Load[TestConfig](
+		templig.WithContext(ctx),
+		templig.WithReaders(strings.NewReader(`name: {{ read "hung/secret.txt" | quote }}`)),
+		templig.WithFS(fsys),
+	)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error %v but got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestLoadContextFunction(t *testing.T) {
+	type ctxKey struct{}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "Name0")
+
+	c, err := templig.Load[TestConfig](
+		templig.WithContext(ctx),
+		templig.WithReaders(strings.NewReader(`name: {{ uPFromContext context | quote }}`)),
+		templig.WithFuncs(template.FuncMap{
+			"uPFromContext": func(ctx context.Context) any { return ctx.Value(ctxKey{}) },
+		}),
+	)
+
+	if err != nil {
+		t.Fatalf("did not expect error using context in template: %v", err)
+	}
+
+	if c.Get().Name != "Name0" {
+		t.Errorf("expected name from context but got %v", c.Get().Name)
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// OutputOption is a functional option to configure the output of a configuration, e.g. using [Config.To].
+type OutputOption func(*outputOptions)
+
+// outputOptions holds the settings of a single output process.
+type outputOptions struct {
+	format Format
+	mask   Mask
+}
+
+// newOutputOptions creates the output options resulting of the application of the given functional options.
+func newOutputOptions(opts ...OutputOption) *outputOptions {
+	result := outputOptions{
+		format: FormatYAML,
+		mask:   MaskLength,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&result)
+		}
+	}
+
+	return &result
+}
+
+// WithOutputFormat sets the format of the output. The default is YAML. In the dotenv format, the keys are the upper
+// case paths of the values with double underscores separating their levels, e.g. `DATABASE__URL=...`. Elements of
+// sequences are given using their index, e.g. `CONN__PASSES__0=...`.
+func WithOutputFormat(format Format) OutputOption {
+	return func(o *outputOptions) {
+		o.format = format
+	}
+}
+
+// WithMask sets the mask used to hide secret values by the ToSecretsHidden family of functions, e.g. [MaskRedacted]
+// or [MaskFingerprint]. The default is [MaskLength].
+func WithMask(mask Mask) OutputOption {
+	return func(o *outputOptions) {
+		if mask != nil {
+			o.mask = mask
+		}
+	}
+}
+
+// writeNode writes the given node structure in the given format to the io.Writer.
+func writeNode(w io.Writer, node *yaml.Node, format Format) error {
+	switch format {
+	case FormatAuto, FormatYAML:
+		return wrapError("could not encode configuration: %w", yaml.NewEncoder(w).Encode(node))
+	case FormatJSON:
+		return writeJSON(w, node)
+	case FormatTOML:
+		return writeTOML(w, node)
+	case FormatEnv:
+		return writeEnv(w, node)
+	default:
+		return fmt.Errorf("could not encode configuration: unhandled %v", format)
+	}
+}
+
+// jsonObject is a JSON object that keeps the order of its members.
+type jsonObject []jsonMember
+
+// jsonMember is a single member of a JSON object.
+type jsonMember struct {
+	key   string
+	value any
+}
+
+// MarshalJSON encodes the object keeping the order of its members.
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, keyErr := json.Marshal(m.key)
+
+		if keyErr != nil {
+			return nil, fmt.Errorf("could not encode key %v: %w", m.key, keyErr)
+		}
+
+		value, valueErr := json.Marshal(m.value)
+
+		if valueErr != nil {
+			return nil, fmt.Errorf("could not encode value of %v: %w", m.key, valueErr)
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// jsonValue converts the given node structure to values that encode to JSON keeping the order of mappings.
+func jsonValue(node *yaml.Node) (any, error) {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+
+		return jsonValue(node.Content[0])
+	case yaml.MappingNode:
+		result := make(jsonObject, 0, len(node.Content)/2) //nolint:mnd
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v, err := jsonValue(node.Content[i+1])
+
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, jsonMember{key: node.Content[i].Value, value: v})
+		}
+
+		return result, nil
+	case yaml.SequenceNode:
+		result := make([]any, 0, len(node.Content))
+
+		for _, c := range node.Content {
+			v, err := jsonValue(c)
+
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, v)
+		}
+
+		return result, nil
+	case yaml.ScalarNode, yaml.AliasNode:
+		var result any
+
+		return result, wrapError("could not convert scalar: %w", node.Decode(&result))
+	default:
+		return nil, fmt.Errorf("unhandled node type %v: %w", node.Kind, ErrNodeTypeUnhandled)
+	}
+}
+
+// writeJSON writes the given node structure as indented JSON.
+func writeJSON(w io.Writer, node *yaml.Node) error {
+	value, valueErr := jsonValue(node)
+
+	if valueErr != nil {
+		return fmt.Errorf("could not encode configuration: %w", valueErr)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+
+	return wrapError("could not encode configuration: %w", encoder.Encode(value))
+}
+
+// writeTOML writes the given node structure as TOML.
+func writeTOML(w io.Writer, node *yaml.Node) error {
+	var value map[string]any
+
+	if err := node.Decode(&value); err != nil {
+		return fmt.Errorf("could not encode configuration: %w", err)
+	}
+
+	return wrapError("could not encode configuration: %w", toml.NewEncoder(w).Encode(value))
+}
+
+// envPlainValue matches values that do not need to be quoted in the dotenv format.
+var envPlainValue = regexp.MustCompile(`^[A-Za-z0-9_./:@+-]*$`)
+
+// writeEnv writes the given node structure in the dotenv format.
+func writeEnv(w io.Writer, node *yaml.Node) error {
+	buf := bytes.Buffer{}
+
+	writeEnvNode(&buf, node, "")
+
+	_, err := w.Write(buf.Bytes())
+
+	return wrapError("could not encode configuration: %w", err)
+}
+
+// writeEnvNode writes the lines of the given node with the given key prefix in the dotenv format.
+func writeEnvNode(buf *bytes.Buffer, node *yaml.Node, key string) {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	switch node.Kind { //nolint:exhaustive
+	case yaml.DocumentNode:
+		for _, c := range node.Content {
+			writeEnvNode(buf, c, key)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			writeEnvNode(buf, node.Content[i+1], envKey(key, node.Content[i].Value))
+		}
+	case yaml.SequenceNode:
+		for i, c := range node.Content {
+			writeEnvNode(buf, c, envKey(key, strconv.Itoa(i)))
+		}
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!null" {
+			return
+		}
+
+		value := node.Value
+
+		if !envPlainValue.MatchString(value) {
+			value = strconv.Quote(value)
+		}
+
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+	}
+}
+
+// envKey appends the given element to the dotenv key.
+func envKey(key string, element string) string {
+	element = strings.ToUpper(element)
+
+	if len(key) == 0 {
+		return element
+	}
+
+	return key + envLevelSeparator + element
+}
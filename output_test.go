@@ -0,0 +1,152 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/AlphaOne1/templig"
+)
+
+func TestOutputFormats(t *testing.T) {
+	c, loadErr := templig.FromFile[TestConfig]("testData/test_config_0.yaml")
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration: %v", loadErr)
+	}
+
+	tests := []struct {
+		name   string
+		format templig.Format
+		hidden bool
+		want   string
+	}{
+		{ // 0
+			name:   "yaml",
+			format: templig.FormatYAML,
+			want:   "id: 9\nname: Name0\nconn:\n    url: https://www.tests.to\n    passes:\n        - pass0\n        - pass1\n",
+		},
+		{ // 1
+			name:   "json",
+			format: templig.FormatJSON,
+			want: `{
+    "id": 9,
+    "name": "Name0",
+    "conn": {
+        "url": "https://www.tests.to",
+        "passes": [
+            "pass0",
+            "pass1"
+        ]
+    }
+}
+`,
+		},
+		{ // 2
+			name:   "toml",
+			format: templig.FormatTOML,
+			want:   "id = 9\nname = \"Name0\"\n\n[conn]\n  passes = [\"pass0\", \"pass1\"]\n  url = \"https://www.tests.to\"\n",
+		},
+		{ // 3
+			name:   "env",
+			format: templig.FormatEnv,
+			want:   "ID=9\nNAME=Name0\nCONN__URL=https://www.tests.to\nCONN__PASSES__0=pass0\nCONN__PASSES__1=pass1\n",
+		},
+		{ // 4
+			name:   "env hidden",
+			format: templig.FormatEnv,
+			hidden: true,
+			want:   "ID=9\nNAME=Name0\nCONN__URL=https://www.tests.to\nCONN__PASSES=\"*\"\n",
+		},
+		{ // 5
+			name:   "json hidden",
+			format: templig.FormatJSON,
+			hidden: true,
+			want: `{
+    "id": 9,
+    "name": "Name0",
+    "conn": {
+        "url": "https://www.tests.to",
+        "passes": "*"
+    }
+}
+`,
+		},
+	}
+
+	for testNum, test := range tests {
+		buf := bytes.Buffer{}
+
+		var toErr error
+
+		if test.hidden {
+			toErr = c.ToSecretsHidden(&buf, templig.WithOutputFormat(test.format))
+		} else {
+			toErr = c.To(&buf, templig.WithOutputFormat(test.format))
+		}
+
+		if toErr != nil {
+			t.Errorf("%v - %v: could not write configuration: %v", testNum, test.name, toErr)
+		}
+
+		if buf.String() != test.want {
+			t.Errorf("%v - %v: wanted\n%v\nbut got\n%v", testNum, test.name, test.want, buf.String())
+		}
+	}
+}
+
+func TestOutputQuotedEnv(t *testing.T) {
+	c, loadErr := templig.From[TestConfig](strings.NewReader(`name: "Name with spaces and \"quotes\""`))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration: %v", loadErr)
+	}
+
+	buf := bytes.Buffer{}
+
+	if err := c.ToSecretsHiddenStructured(&buf, templig.WithOutputFormat(templig.FormatEnv)); err != nil {
+		t.Errorf("could not write configuration: %v", err)
+	}
+
+	if want := "ID=0\nNAME=\"Name with spaces and \\\"quotes\\\"\"\n"; buf.String() != want {
+		t.Errorf("wanted\n%v\nbut got\n%v", want, buf.String())
+	}
+}
+
+func TestOutputFileRoundTrip(t *testing.T) {
+	c, loadErr := templig.FromFile[TestConfig]("testData/test_config_0.yaml")
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration: %v", loadErr)
+	}
+
+	for _, ext := range []string{".yaml", ".json", ".toml", ".env"} {
+		path := filepath.Join(t.TempDir(), "config"+ext)
+
+		if err := c.ToFile(path); err != nil {
+			t.Errorf("%v: could not write configuration: %v", ext, err)
+
+			continue
+		}
+
+		read, readErr := templig.FromFile[TestConfig](path)
+
+		if readErr != nil {
+			t.Errorf("%v: could not read written configuration: %v", ext, readErr)
+
+			continue
+		}
+
+		if read.Get().ID != c.Get().ID ||
+			read.Get().Name != c.Get().Name ||
+			read.Get().Conn.URL != c.Get().Conn.URL ||
+			!slices.Equal(read.Get().Conn.Passes, c.Get().Conn.Passes) {
+			t.Errorf("%v: written configuration %+v differs from original %+v", ext, read.Get(), c.Get())
+		}
+	}
+}
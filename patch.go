@@ -0,0 +1,528 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrInvalidPatch indicates that a patch is not of the form required by its standard.
+	ErrInvalidPatch = errors.New("invalid patch")
+
+	// ErrPatchPathNotFound indicates that the path of a JSON Patch operation does not exist in the configuration.
+	ErrPatchPathNotFound = errors.New("patch path not found")
+
+	// ErrPatchTestFailed indicates that a `test` operation of a JSON Patch did not succeed.
+	ErrPatchTestFailed = errors.New("patch test failed")
+)
+
+// patchKind is the standard of a patch.
+type patchKind int
+
+const (
+	// mergePatch is a JSON Merge Patch according to RFC 7386.
+	mergePatch patchKind = iota
+	// jsonPatch is a JSON Patch according to RFC 6902.
+	jsonPatch
+)
+
+// patch is a patch applied to the merged configuration.
+type patch struct {
+	kind patchKind
+	src  source
+}
+
+// WithMergePatch applies the JSON Merge Patch (RFC 7386) read from the given io.Reader to the configuration, after all
+// configuration sources were overlaid. The patch is given in YAML or JSON. In contrast to overlays, `null` values
+// delete the corresponding keys and all values but mappings replace the values of the configuration.
+func WithMergePatch(r io.Reader) Option {
+	return func(o *options) {
+		o.patches = append(o.patches, patch{
+			kind: mergePatch,
+			src:  readerSource(fmt.Sprintf("merge patch %d", len(o.patches)), r),
+		})
+	}
+}
+
+// WithJSONPatch applies the JSON Patch (RFC 6902) read from the given io.Reader to the configuration, after all
+// configuration sources were overlaid. The patch is given in YAML or JSON as sequence of operations, supporting `add`,
+// `remove`, `replace`, `move`, `copy` and `test`. The empty path refers to the whole document, that can be replaced,
+// but not removed.
+func WithJSONPatch(r io.Reader) Option {
+	return func(o *options) {
+		o.patches = append(o.patches, patch{
+			kind: jsonPatch,
+			src:  readerSource(fmt.Sprintf("JSON patch %d", len(o.patches)), r),
+		})
+	}
+}
+
+// MergePatchYAMLNodes applies the JSON Merge Patch (RFC 7386) given as node structure to the node `target`.
+func MergePatchYAMLNodes(target, mergePatch *yaml.Node) (*yaml.Node, error) {
+	if target == nil || mergePatch == nil {
+		return nil, ErrNodeNil
+	}
+
+	m := new(merger)
+	res := m.mergePatch(target, mergePatch)
+
+	m.fixAnchors(res)
+
+	return res, nil
+}
+
+// PatchYAMLNodes applies the JSON Patch (RFC 6902) given as node structure to the node `target`.
+func PatchYAMLNodes(target, operations *yaml.Node) (*yaml.Node, error) {
+	if target == nil || operations == nil {
+		return nil, ErrNodeNil
+	}
+
+	return new(merger).jsonPatch(target, operations)
+}
+
+// applyPatch reads the given patch and applies it to the current intermediate configuration.
+func (c *Config[T]) applyPatch(p patch, o *options) error {
+	content, err := p.src.read(o.ctx)
+
+	if err != nil {
+		return fmt.Errorf("could not read %v: %w", p.src.name, err)
+	}
+
+	docs, docsErr := parseNodes(content, FormatYAML)
+
+	if docsErr != nil {
+		return fmt.Errorf("could not parse %v: %w", p.src.name, docsErr)
+	}
+
+	if c.node == nil {
+		c.node = documentNode(containerNode(reflect.TypeFor[T]()))
+	}
+
+	for _, doc := range docs {
+		c.merger.addLayer(p.src.name, doc)
+
+		if emptyDocument(doc) {
+			continue
+		}
+
+		switch p.kind {
+		case mergePatch:
+			c.node = c.merger.mergePatch(c.node, doc)
+		case jsonPatch:
+			patched, patchErr := c.merger.jsonPatch(c.node, doc)
+
+			if patchErr != nil {
+				return fmt.Errorf("could not apply %v: %w", p.src.name, patchErr)
+			}
+
+			c.node = patched
+		}
+
+		c.merger.fixAnchors(c.node)
+	}
+
+	return nil
+}
+
+// isNull checks if the given node is a null value.
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null"
+}
+
+// resolveAlias gives the node finally referred to by the given node.
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	return node
+}
+
+// mergePatch applies the JSON Merge Patch `p` to the node `target`, which may be nil, if there is no target value.
+func (m *merger) mergePatch(target, p *yaml.Node) *yaml.Node {
+	p = resolveAlias(p)
+
+	if target != nil {
+		target = resolveAlias(target)
+	}
+
+	if p.Kind == yaml.DocumentNode {
+		if len(p.Content) == 0 && target != nil {
+			// empty patches, e.g. consisting of comments only, change nothing
+			return target
+		}
+
+		if len(p.Content) == 0 || target == nil || target.Kind != yaml.DocumentNode || len(target.Content) != 1 {
+			return m.copyNode(p)
+		}
+
+		ret := m.copyNode(target)
+		ret.Content = []*yaml.Node{m.mergePatch(target.Content[0], p.Content[0])}
+
+		return ret
+	}
+
+	if p.Kind != yaml.MappingNode {
+		if target == nil {
+			return p
+		}
+
+		return m.override(target, p)
+	}
+
+	var ret *yaml.Node
+
+	if target != nil && target.Kind == yaml.MappingNode {
+		ret = m.copyNode(target)
+		ret.Content = slices.Clone(target.Content)
+	} else {
+		ret = m.copyNode(p)
+		ret.Content = nil
+		ret.Anchor = ""
+	}
+
+	for i := 0; i+1 < len(p.Content); i += 2 {
+		key, value := p.Content[i], resolveAlias(p.Content[i+1])
+		index := mappingIndex(ret, key.Value)
+
+		switch {
+		case isNull(value) && index >= 0:
+			ret.Content = slices.Delete(ret.Content, index, index+2)
+		case isNull(value):
+			// nothing to delete
+		case index >= 0:
+			ret.Content[index+1] = m.mergePatch(ret.Content[index+1], value)
+		default:
+			ret.Content = append(ret.Content, key, m.mergePatch(nil, value))
+		}
+	}
+
+	return ret
+}
+
+// mappingIndex gives the index of the given key in the mapping node, or -1 if it is not present.
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// patchOperation is a single operation of a JSON Patch.
+type patchOperation struct {
+	Op    string     `yaml:"op"`
+	Path  string     `yaml:"path"`
+	From  string     `yaml:"from"`
+	Value *yaml.Node `yaml:"-"`
+}
+
+// jsonPatch applies the JSON Patch operations to a deep copy of the node `target`.
+func (m *merger) jsonPatch(target, operations *yaml.Node) (*yaml.Node, error) {
+	if operations.Kind == yaml.DocumentNode && len(operations.Content) == 1 {
+		operations = operations.Content[0]
+	}
+
+	if operations.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%w: JSON Patch has to be a sequence of operations", ErrInvalidPatch)
+	}
+
+	root := m.deepCopy(target, true)
+
+	// aliases are redirected to the copies of their targets, so that operations through them change the copy
+	m.fixAnchors(root)
+
+	for i, opNode := range operations.Content {
+		var op patchOperation
+
+		if err := opNode.Decode(&op); err != nil {
+			return nil, fmt.Errorf("%w: operation %v: %w", ErrInvalidPatch, i, err)
+		}
+
+		// the value is taken as is, to keep its origin
+		if index := mappingIndex(opNode, "value"); index >= 0 {
+			op.Value = opNode.Content[index+1]
+		}
+
+		var opErr error
+
+		if root, opErr = m.applyOperation(root, op); opErr != nil {
+			return nil, fmt.Errorf("operation %v (%v %v): %w", i, op.Op, op.Path, opErr)
+		}
+	}
+
+	m.fixAnchors(root)
+
+	return root, nil
+}
+
+// deepCopy copies the given node structure, keeping the origins of the nodes. If the copy replaces the original, its
+// aliases are redirected to the copy, otherwise the anchors are removed from the copy.
+func (m *merger) deepCopy(node *yaml.Node, replace bool) *yaml.Node {
+	ret := m.copyNode(node)
+
+	if !replace {
+		ret.Anchor = ""
+	} else if len(node.Anchor) > 0 {
+		if m.replaced == nil {
+			m.replaced = make(map[*yaml.Node]*yaml.Node)
+		}
+
+		m.replaced[node] = ret
+	}
+
+	ret.Content = make([]*yaml.Node, len(node.Content))
+
+	for i, c := range node.Content {
+		ret.Content[i] = m.deepCopy(c, replace)
+	}
+
+	return ret
+}
+
+// applyOperation applies a single JSON Patch operation to the given document.
+func (m *merger) applyOperation(root *yaml.Node, op patchOperation) (*yaml.Node, error) {
+	needsValue := op.Op == "add" || op.Op == "replace" || op.Op == "test"
+
+	if needsValue && op.Value == nil {
+		return nil, fmt.Errorf("%w: value missing", ErrInvalidPatch)
+	}
+
+	switch op.Op {
+	case "add":
+		return m.add(root, op.Path, op.Value, false)
+	case "remove":
+		_, err := m.pointerRemove(root, op.Path)
+
+		return root, err
+	case "replace":
+		return m.add(root, op.Path, op.Value, true)
+	case "move":
+		value, err := m.pointerRemove(root, op.From)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return m.add(root, op.Path, value, false)
+	case "copy":
+		value, err := pointerGet(root, op.From)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return m.add(root, op.Path, m.deepCopy(value, false), false)
+	case "test":
+		value, err := pointerGet(root, op.Path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !equalNodes(value, op.Value) {
+			return nil, ErrPatchTestFailed
+		}
+
+		return root, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+// add sets the value the JSON Pointer refers to like pointerAdd and gives the resulting document. The empty pointer
+// replaces the whole document.
+func (m *merger) add(root *yaml.Node, pointer string, value *yaml.Node, replace bool) (*yaml.Node, error) {
+	if len(pointer) > 0 {
+		return root, m.pointerAdd(root, pointer, value, replace)
+	}
+
+	if root.Kind != yaml.DocumentNode {
+		return m.override(root, value), nil
+	}
+
+	if len(root.Content) == 1 {
+		root.Content[0] = m.override(root.Content[0], value)
+	} else {
+		root.Content = []*yaml.Node{value}
+	}
+
+	return root, nil
+}
+
+// pointerTokens splits the given JSON Pointer (RFC 6901) into its unescaped reference tokens.
+func pointerTokens(pointer string) ([]string, error) {
+	if len(pointer) == 0 {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: pointer %q does not start with /", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// pointerParent gives the container of the value the JSON Pointer refers to and the last reference token.
+func pointerParent(root *yaml.Node, pointer string) (*yaml.Node, string, error) {
+	tokens, err := pointerTokens(pointer)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(tokens) == 0 {
+		return nil, "", fmt.Errorf("%w: operation on the whole document", ErrInvalidPatch)
+	}
+
+	node := root
+
+	if node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+		node = node.Content[0]
+	}
+
+	for _, t := range tokens[:len(tokens)-1] {
+		if node, err = pointerChild(node, t); err != nil {
+			return nil, "", fmt.Errorf("%w: %v", err, pointer)
+		}
+	}
+
+	return resolveAlias(node), tokens[len(tokens)-1], nil
+}
+
+// pointerChild gives the child of the given node referred to by the reference token.
+func pointerChild(node *yaml.Node, token string) (*yaml.Node, error) {
+	node = resolveAlias(node)
+
+	switch node.Kind { //nolint:exhaustive
+	case yaml.MappingNode:
+		if index := mappingIndex(node, token); index >= 0 {
+			return node.Content[index+1], nil
+		}
+	case yaml.SequenceNode:
+		if index, err := strconv.Atoi(token); err == nil && index >= 0 && index < len(node.Content) {
+			return node.Content[index], nil
+		}
+	}
+
+	return nil, ErrPatchPathNotFound
+}
+
+// pointerGet gives the value the JSON Pointer refers to. The empty pointer refers to the whole document.
+func pointerGet(root *yaml.Node, pointer string) (*yaml.Node, error) {
+	if len(pointer) == 0 {
+		if root.Kind == yaml.DocumentNode && len(root.Content) == 1 {
+			return root.Content[0], nil
+		}
+
+		return root, nil
+	}
+
+	parent, token, err := pointerParent(root, pointer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	child, childErr := pointerChild(parent, token)
+
+	if childErr != nil {
+		return nil, fmt.Errorf("%w: %v", childErr, pointer)
+	}
+
+	return child, nil
+}
+
+// pointerAdd sets the value the JSON Pointer refers to. Elements of sequences are inserted, unless existing values are
+// to be replaced.
+func (m *merger) pointerAdd(root *yaml.Node, pointer string, value *yaml.Node, replace bool) error {
+	parent, token, err := pointerParent(root, pointer)
+
+	if err != nil {
+		return err
+	}
+
+	switch parent.Kind { //nolint:exhaustive
+	case yaml.MappingNode:
+		if index := mappingIndex(parent, token); index >= 0 {
+			parent.Content[index+1] = m.override(parent.Content[index+1], value)
+
+			return nil
+		}
+
+		if !replace {
+			key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: token}
+			parent.Content = append(parent.Content, key, value)
+
+			return nil
+		}
+	case yaml.SequenceNode:
+		if token == "-" && !replace {
+			parent.Content = append(parent.Content, value)
+
+			return nil
+		}
+
+		index, indexErr := strconv.Atoi(token)
+
+		switch {
+		case indexErr != nil || index < 0 || index > len(parent.Content):
+		case replace && index < len(parent.Content):
+			parent.Content[index] = m.override(parent.Content[index], value)
+
+			return nil
+		case !replace:
+			parent.Content = slices.Insert(parent.Content, index, value)
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %v", ErrPatchPathNotFound, pointer)
+}
+
+// pointerRemove removes the value the JSON Pointer refers to and gives it.
+func (m *merger) pointerRemove(root *yaml.Node, pointer string) (*yaml.Node, error) {
+	parent, token, err := pointerParent(root, pointer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch parent.Kind { //nolint:exhaustive
+	case yaml.MappingNode:
+		if index := mappingIndex(parent, token); index >= 0 {
+			value := parent.Content[index+1]
+			parent.Content = slices.Delete(parent.Content, index, index+2)
+
+			return value, nil
+		}
+	case yaml.SequenceNode:
+		if index, indexErr := strconv.Atoi(token); indexErr == nil && index >= 0 && index < len(parent.Content) {
+			value := parent.Content[index]
+			parent.Content = slices.Delete(parent.Content, index, index+1)
+
+			return value, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %v", ErrPatchPathNotFound, pointer)
+}
@@ -0,0 +1,285 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/AlphaOne1/templig"
+)
+
+func decodeNode(t *testing.T, node *yaml.Node) any {
+	t.Helper()
+
+	var result any
+
+	if err := node.Decode(&result); err != nil {
+		t.Fatalf("could not decode node: %v", err)
+	}
+
+	return result
+}
+
+func encodeNode(t *testing.T, node *yaml.Node) string {
+	t.Helper()
+
+	buf := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(node); err != nil {
+		t.Fatalf("could not encode node: %v", err)
+	}
+
+	return buf.String()
+}
+
+func parseNode(t *testing.T, content string) *yaml.Node {
+	t.Helper()
+
+	var node yaml.Node
+
+	if err := yaml.Unmarshal([]byte(content), &node); err != nil {
+		t.Fatalf("could not parse node: %v", err)
+	}
+
+	return &node
+}
+
+func TestMergePatchYAMLNodes(t *testing.T) {
+	// test cases of RFC 7386, appendix A
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a": "c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a": "b", "b": "c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b": "c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a": "c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a": ["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a": {"b": "d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a": [1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c", "d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e": null, "a": 1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a": "b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a": {"bb": {}}}`},
+	}
+
+	for i, test := range tests {
+		res, resErr := templig.MergePatchYAMLNodes(parseNode(t, test.target), parseNode(t, test.patch))
+
+		if resErr != nil {
+			t.Errorf("%v: could not apply merge patch: %v", i, resErr)
+
+			continue
+		}
+
+		if got, want := encodeNode(t, res), encodeNode(t, parseNode(t, test.want)); got != want {
+			t.Errorf("%v: expected\n%v\nbut got\n%v", i, want, got)
+		}
+	}
+}
+
+func TestMergePatchYAMLNodesEmpty(t *testing.T) {
+	target := parseNode(t, `{"a": "b"}`)
+
+	res, resErr := templig.MergePatchYAMLNodes(target, &yaml.Node{Kind: yaml.DocumentNode})
+
+	if resErr != nil {
+		t.Fatalf("could not apply empty merge patch: %v", resErr)
+	}
+
+	if got, want := encodeNode(t, res), encodeNode(t, target); got != want {
+		t.Errorf("expected empty patch to change nothing, got\n%v", got)
+	}
+}
+
+func TestPatchYAMLNodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "add member",
+			target: `{"foo": "bar"}`,
+			patch:  `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			want:   `{"foo": "bar", "baz": "qux"}`,
+		},
+		{
+			name:   "add array element",
+			target: `{"foo": ["bar", "baz"]}`,
+			patch:  `[{"op": "add", "path": "/foo/1", "value": "qux"}, {"op": "add", "path": "/foo/-", "value": "end"}]`,
+			want:   `{"foo": ["bar", "qux", "baz", "end"]}`,
+		},
+		{
+			name:   "remove",
+			target: `{"baz": "qux", "foo": ["bar", "qux", "baz"]}`,
+			patch:  `[{"op": "remove", "path": "/baz"}, {"op": "remove", "path": "/foo/1"}]`,
+			want:   `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:   "replace",
+			target: `{"baz": "qux", "foo": "bar"}`,
+			patch:  `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			want:   `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:   "move",
+			target: `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch:  `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:   `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			name:   "copy and escaped pointer",
+			target: `{"a/b": {"c~d": 1}}`,
+			patch:  `[{"op": "copy", "from": "/a~1b/c~0d", "path": "/e"}]`,
+			want:   `{"a/b": {"c~d": 1}, "e": 1}`,
+		},
+		{
+			name: "yaml test success",
+			target: `
+conn:
+  url: https://a
+`,
+			patch: `
+- op: test
+  path: /conn/url
+  value: https://a
+- op: replace
+  path: /conn/url
+  value: https://b
+`,
+			want: `{"conn": {"url": "https://b"}}`,
+		},
+		{
+			name:   "replace document",
+			target: `{"foo": "bar"}`,
+			patch:  `[{"op": "test", "path": "", "value": {"foo": "bar"}}, {"op": "replace", "path": "", "value": [1]}]`,
+			want:   `[1]`,
+		},
+		{
+			name:   "add document",
+			target: `{"foo": "bar"}`,
+			patch:  `[{"op": "add", "path": "", "value": {"baz": "qux"}}]`,
+			want:   `{"baz": "qux"}`,
+		},
+		{
+			name: "replace through alias",
+			target: `
+a: &x {k: 1}
+b: *x
+`,
+			patch: `[{"op": "replace", "path": "/b/k", "value": 2}]`,
+			want:  `{"a": {"k": 2}, "b": {"k": 2}}`,
+		},
+		{
+			name:    "remove document",
+			target:  `{"foo": "bar"}`,
+			patch:   `[{"op": "remove", "path": ""}]`,
+			wantErr: templig.ErrInvalidPatch,
+		},
+		{
+			name:    "test failure",
+			target:  `{"baz": "qux"}`,
+			patch:   `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			wantErr: templig.ErrPatchTestFailed,
+		},
+		{
+			name:    "replace missing",
+			target:  `{"baz": "qux"}`,
+			patch:   `[{"op": "replace", "path": "/foo", "value": "bar"}]`,
+			wantErr: templig.ErrPatchPathNotFound,
+		},
+		{
+			name:    "unknown operation",
+			target:  `{"baz": "qux"}`,
+			patch:   `[{"op": "jump", "path": "/baz"}]`,
+			wantErr: templig.ErrInvalidPatch,
+		},
+		{
+			name:    "no sequence",
+			target:  `{"baz": "qux"}`,
+			patch:   `{"op": "remove", "path": "/baz"}`,
+			wantErr: templig.ErrInvalidPatch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := parseNode(t, test.target)
+			before := encodeNode(t, target)
+
+			res, resErr := templig.PatchYAMLNodes(target, parseNode(t, test.patch))
+
+			if test.wantErr != nil {
+				if !errors.Is(resErr, test.wantErr) {
+					t.Errorf("expected error %v but got %v", test.wantErr, resErr)
+				}
+
+				return
+			}
+
+			if resErr != nil {
+				t.Fatalf("could not apply patch: %v", resErr)
+			}
+
+			if got, want := decodeNode(t, res), decodeNode(t, parseNode(t, test.want)); !reflect.DeepEqual(got, want) {
+				t.Errorf("expected %v but got %v", want, got)
+			}
+
+			if after := encodeNode(t, target); after != before {
+				t.Errorf("patching must not change the target, got\n%v", after)
+			}
+		})
+	}
+}
+
+func TestLoadPatches(t *testing.T) {
+	c, loadErr := templig.Load[TestConfig](
+		templig.WithFiles("testData/test_config_0.yaml"),
+		templig.WithMergePatch(strings.NewReader(`{"name": "Patched", "conn": null}`)),
+		templig.WithJSONPatch(strings.NewReader(`[{"op": "replace", "path": "/id", "value": 42}]`)),
+	)
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration with patches: %v", loadErr)
+	}
+
+	if c.Get().ID != 42 || c.Get().Name != "Patched" || c.Get().Conn != nil {
+		t.Errorf("unexpected configuration: %+v", c.Get())
+	}
+
+	if p, _ := c.Explain("id"); p.Source != "JSON patch 1" || len(p.Overridden) != 1 {
+		t.Errorf("unexpected provenance of patched value: %v", p)
+	}
+
+	if p, _ := c.Explain("name"); p.Source != "merge patch 0" || p.Line != 1 {
+		t.Errorf("unexpected provenance of merge patched value: %v", p)
+	}
+}
+
+func TestLoadPatchFailure(t *testing.T) {
+	_, loadErr := templig.Load[TestConfig](
+		templig.WithFiles("testData/test_config_0.yaml"),
+		templig.WithJSONPatch(strings.NewReader(`[{"op": "remove", "path": "/unknown"}]`)),
+	)
+
+	if !errors.Is(loadErr, templig.ErrPatchPathNotFound) {
+		t.Errorf("expected error %v but got %v", templig.ErrPatchPathNotFound, loadErr)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ProfilesEnvVar is the environment variable giving the comma-separated active profiles, if none are given using
+// [WithProfiles] and [WithProfilesFromEnv] is used.
+const ProfilesEnvVar = "TEMPLIG_PROFILES"
+
+// ErrProfileMissing indicates that no file was found for a required profile.
+var ErrProfileMissing = errors.New("required profile missing")
+
+// WithProfiles sets the active profiles. For every configuration file, e.g. `config.yaml`, the files of the active
+// profiles, e.g. `config.prod.yaml` and `config.eu.yaml` for the profiles `prod` and `eu`, are overlaid directly after
+// it, in the order of the profiles. Files of profiles that do not exist or are already configuration sources are
+// skipped.
+func WithProfiles(profiles ...string) Option {
+	return func(o *options) {
+		o.profiles = append(o.profiles, profiles...)
+	}
+}
+
+// WithRequiredProfiles activates the given profiles like [WithProfiles], but fails with [ErrProfileMissing], if no
+// file is found for one of them.
+func WithRequiredProfiles(profiles ...string) Option {
+	return func(o *options) {
+		o.profiles = append(o.profiles, profiles...)
+		o.requiredProfiles = append(o.requiredProfiles, profiles...)
+	}
+}
+
+// WithProfilesFromEnv activates the profiles given comma-separated by the environment variable [ProfilesEnvVar], e.g.
+// `TEMPLIG_PROFILES=prod,eu`, if no profiles are given using [WithProfiles].
+func WithProfilesFromEnv() Option {
+	return func(o *options) {
+		o.profilesFromEnv = true
+	}
+}
+
+// activeProfiles gives the active profiles, either given by options or, if enabled, by the environment.
+func (o *options) activeProfiles() []string {
+	if len(o.profiles) > 0 || !o.profilesFromEnv {
+		return o.profiles
+	}
+
+	var result []string
+
+	for _, p := range strings.Split(os.Getenv(ProfilesEnvVar), ",") {
+		if p = strings.TrimSpace(p); len(p) > 0 {
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
+
+// profilePath gives the path of the file of the given profile for the configuration file, e.g. `config.prod.yaml`
+// for `config.yaml`.
+func profilePath(file string, profile string) string {
+	ext := filepath.Ext(file)
+
+	return strings.TrimSuffix(file, ext) + "." + profile + ext
+}
+
+// stat gives the file information of the file with the given name, either from the file system given by [WithFS] or
+// the operating system.
+func (o *options) stat(name string) (fs.FileInfo, error) {
+	if o.fsys != nil {
+		return fs.Stat(o.fsys, path.Clean(filepath.ToSlash(name))) //nolint:wrapcheck
+	}
+
+	return os.Stat(filepath.Clean(name)) //nolint:wrapcheck
+}
+
+// profileSources gives the configuration sources including the files of the active profiles.
+func (o *options) profileSources() ([]source, error) {
+	profiles := o.activeProfiles()
+
+	if len(profiles) == 0 {
+		return o.sources, nil
+	}
+
+	result := make([]source, 0, len(o.sources)*(len(profiles)+1))
+	found := make(map[string]bool, len(profiles))
+
+	for _, src := range o.sources {
+		result = append(result, src)
+
+		if len(src.path) == 0 {
+			continue
+		}
+
+		for _, p := range profiles {
+			candidate := profilePath(src.path, p)
+
+			if o.hasSource(candidate) {
+				// given explicitly, so it is already applied
+				found[p] = true
+
+				continue
+			}
+
+			if o.fileExists(candidate) {
+				found[p] = true
+				result = append(result, o.fileSource(candidate))
+			}
+		}
+	}
+
+	var errs []error
+
+	for _, p := range slices.Compact(slices.Sorted(slices.Values(o.requiredProfiles))) {
+		if !found[p] {
+			errs = append(errs, fmt.Errorf("%w: %v", ErrProfileMissing, p))
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// hasSource checks if the file with the given name is one of the configuration sources.
+func (o *options) hasSource(name string) bool {
+	return slices.ContainsFunc(o.sources, func(src source) bool {
+		return len(src.path) > 0 && filepath.Clean(src.path) == filepath.Clean(name)
+	})
+}
+
+// fileExists checks if the file with the given name exists.
+func (o *options) fileExists(name string) bool {
+	_, err := o.stat(name)
+
+	return err == nil
+}
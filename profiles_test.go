@@ -0,0 +1,138 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/AlphaOne1/templig"
+)
+
+func testProfilesFS() fstest.MapFS {
+	return fstest.MapFS{
+		"config.yaml":      {Data: []byte("id: 1\nname: base\n")},
+		"config.prod.yaml": {Data: []byte("name: prod\n")},
+		"config.eu.yaml":   {Data: []byte("name: eu\nconn:\n  url: https://eu\n")},
+		"local.yaml":       {Data: []byte("id: 2\n")},
+		"local.eu.yaml":    {Data: []byte("id: 3\n")},
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         string
+		opts        []templig.Option
+		wantName    string
+		wantID      int
+		wantSources []string
+		wantErr     error
+	}{
+		{
+			name:        "no profiles",
+			wantName:    "base",
+			wantID:      2,
+			wantSources: []string{"config.yaml", "local.yaml"},
+		},
+		{
+			name:        "option profiles",
+			opts:        []templig.Option{templig.WithProfiles("prod", "eu")},
+			wantName:    "eu",
+			wantID:      3,
+			wantSources: []string{"config.yaml", "config.prod.yaml", "config.eu.yaml", "local.yaml", "local.eu.yaml"},
+		},
+		{
+			name:        "profile order",
+			opts:        []templig.Option{templig.WithProfiles("eu", "prod")},
+			wantName:    "prod",
+			wantID:      3,
+			wantSources: []string{"config.yaml", "config.eu.yaml", "config.prod.yaml", "local.yaml", "local.eu.yaml"},
+		},
+		{
+			name:        "environment profiles",
+			env:         "prod, unknown",
+			opts:        []templig.Option{templig.WithProfilesFromEnv()},
+			wantName:    "prod",
+			wantID:      2,
+			wantSources: []string{"config.yaml", "config.prod.yaml", "local.yaml"},
+		},
+		{
+			name:        "option before environment",
+			env:         "eu",
+			opts:        []templig.Option{templig.WithProfiles("prod"), templig.WithProfilesFromEnv()},
+			wantName:    "prod",
+			wantID:      2,
+			wantSources: []string{"config.yaml", "config.prod.yaml", "local.yaml"},
+		},
+		{
+			name:        "environment not enabled",
+			env:         "prod",
+			wantName:    "base",
+			wantID:      2,
+			wantSources: []string{"config.yaml", "local.yaml"},
+		},
+		{
+			name:     "required present",
+			opts:     []templig.Option{templig.WithRequiredProfiles("prod")},
+			wantName: "prod",
+			wantID:   2,
+		},
+		{
+			name:    "required missing",
+			opts:    []templig.Option{templig.WithProfiles("prod"), templig.WithRequiredProfiles("test")},
+			wantErr: templig.ErrProfileMissing,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(templig.ProfilesEnvVar, test.env)
+
+			opts := append([]templig.Option{
+				templig.WithFS(testProfilesFS()),
+				templig.WithFiles("config.yaml", "local.yaml"),
+			}, test.opts...)
+
+			c, loadErr := templig.Load[TestConfig](opts...)
+
+			if test.wantErr != nil {
+				if !errors.Is(loadErr, test.wantErr) {
+					t.Errorf("expected error %v but got %v", test.wantErr, loadErr)
+				}
+
+				return
+			}
+
+			if loadErr != nil {
+				t.Fatalf("could not load configuration with profiles: %v", loadErr)
+			}
+
+			if c.Get().Name != test.wantName || c.Get().ID != test.wantID {
+				t.Errorf("unexpected configuration: %+v", c.Get())
+			}
+
+			if test.wantSources != nil && !slices.Equal(c.Sources(), test.wantSources) {
+				t.Errorf("expected sources %v but got %v", test.wantSources, c.Sources())
+			}
+		})
+	}
+}
+
+func TestProfilesExplicitFile(t *testing.T) {
+	c, loadErr := templig.Load[TestConfig](
+		templig.WithFS(testProfilesFS()),
+		templig.WithFiles("config.yaml", "config.prod.yaml"),
+		templig.WithRequiredProfiles("prod"))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration with profiles: %v", loadErr)
+	}
+
+	if expected := []string{"config.yaml", "config.prod.yaml"}; !slices.Equal(c.Sources(), expected) {
+		t.Errorf("expected sources %v but got %v", expected, c.Sources())
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Origin describes the source location a configuration value was read from.
+type Origin struct {
+	// Source is the name of the source, e.g. the file name.
+	Source string
+	// Layer is the index of the source in loading order, the base being 0. Unknown origins have layer -1.
+	Layer int
+	// Line is the line of the value in the source.
+	Line int
+	// Column is the column of the value in the source.
+	Column int
+}
+
+// String gives a human-readable representation of the origin.
+func (o Origin) String() string {
+	return fmt.Sprintf("%v, line %v, column %v (layer %v)", o.Source, o.Line, o.Column, o.Layer)
+}
+
+// Provenance describes where a configuration value was defined and which earlier definitions it replaced.
+type Provenance struct {
+	Origin
+
+	// Overridden contains the origins of the definitions replaced by this value, the oldest first.
+	Overridden []Origin
+}
+
+// String gives a human-readable explanation of the provenance.
+func (p Provenance) String() string {
+	b := strings.Builder{}
+
+	b.WriteString("defined in ")
+	b.WriteString(p.Origin.String())
+
+	for i := len(p.Overridden) - 1; i >= 0; i-- {
+		b.WriteString(", overriding ")
+		b.WriteString(p.Overridden[i].String())
+	}
+
+	return b.String()
+}
+
+// Provenance gives the provenance of all values of the configuration, indexed by their dotted path. Elements of
+// sequences are addressed using their index, e.g. `conn.passes.1`. The root of the configuration has the empty path.
+func (c *Config[T]) Provenance() map[string]Provenance {
+	return maps.Clone(c.provenance)
+}
+
+// Explain gives the provenance of the value with the given dotted path. The second return value indicates if the
+// path was found in the configuration.
+func (c *Config[T]) Explain(path string) (Provenance, bool) {
+	p, found := c.provenance[path]
+
+	return p, found
+}
+
+// buildProvenance collects the provenance of the given node structure and all its children.
+func (m *merger) buildProvenance(node *yaml.Node) map[string]Provenance {
+	result := make(map[string]Provenance)
+
+	m.collectProvenance(result, node, "", make(map[*yaml.Node]bool))
+
+	return result
+}
+
+// collectProvenance adds the provenance of the given node and all its children to the result.
+func (m *merger) collectProvenance(
+	result map[string]Provenance,
+	node *yaml.Node,
+	path string,
+	visiting map[*yaml.Node]bool,
+) {
+	if node == nil || visiting[node] {
+		return
+	}
+
+	if node.Kind == yaml.DocumentNode {
+		for _, v := range node.Content {
+			m.collectProvenance(result, v, path, visiting)
+		}
+
+		return
+	}
+
+	p := Provenance{Origin: m.origin(node)}
+
+	if o, found := m.origins[node]; found {
+		p.Overridden = o.overridden
+	}
+
+	result[path] = p
+
+	// the children of aliases are given using the path of the alias
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	if visiting[node] {
+		return
+	}
+
+	visiting[node] = true
+	defer delete(visiting, node)
+
+	switch node.Kind { //nolint:exhaustive
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			m.collectProvenance(result, node.Content[i+1], joinPath(path, node.Content[i].Value), visiting)
+		}
+	case yaml.SequenceNode:
+		for i, v := range node.Content {
+			m.collectProvenance(result, v, joinPath(path, strconv.Itoa(i)), visiting)
+		}
+	}
+}
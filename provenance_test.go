@@ -0,0 +1,88 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AlphaOne1/templig"
+)
+
+func TestProvenance(t *testing.T) {
+	c, loadErr := templig.Load[TestConfig](
+		templig.WithFiles("testData/test_config_0.yaml", "testData/test_config_0_overlay.yaml"),
+		templig.WithReaders(strings.NewReader("name: Name1")),
+	)
+
+	if loadErr != nil {
+		t.Fatalf("no error expected loading overlays: %v", loadErr)
+	}
+
+	tests := []struct {
+		path           string
+		wantSource     string
+		wantLayer      int
+		wantLine       int
+		wantOverridden int
+	}{
+		{ // 0
+			path:       "id",
+			wantSource: "testData/test_config_0.yaml",
+			wantLayer:  0,
+			wantLine:   4,
+		},
+		{ // 1
+			path:           "name",
+			wantSource:     "reader 2",
+			wantLayer:      2,
+			wantLine:       1,
+			wantOverridden: 1,
+		},
+		{ // 2
+			path:       "conn.passes.1",
+			wantSource: "testData/test_config_0.yaml",
+			wantLayer:  0,
+			wantLine:   10,
+		},
+		{ // 3
+			path:       "conn.passes.2",
+			wantSource: "testData/test_config_0_overlay.yaml",
+			wantLayer:  1,
+			wantLine:   3,
+		},
+	}
+
+	for testNum, test := range tests {
+		p, found := c.Explain(test.path)
+
+		if !found {
+			t.Errorf("%v: expected provenance of %v to be found", testNum, test.path)
+
+			continue
+		}
+
+		if p.Source != test.wantSource || p.Layer != test.wantLayer || p.Line != test.wantLine {
+			t.Errorf("%v: wanted %v in %v (layer %v), line %v but got %v",
+				testNum, test.path, test.wantSource, test.wantLayer, test.wantLine, p)
+		}
+
+		if len(p.Overridden) != test.wantOverridden {
+			t.Errorf("%v: wanted %v overridden definitions but got %v",
+				testNum, test.wantOverridden, len(p.Overridden))
+		}
+	}
+
+	if p, _ := c.Explain("name"); !strings.Contains(p.String(), "overriding testData/test_config_0.yaml, line 5") {
+		t.Errorf("explanation does not mention the overridden definition: %v", p)
+	}
+
+	if _, found := c.Explain("conn.unknown"); found {
+		t.Errorf("did not expect provenance for unknown path")
+	}
+
+	if len(c.Provenance()) != 9 {
+		t.Errorf("expected provenance of 9 paths but got %v", len(c.Provenance()))
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrUnknownSecretProvider is returned if a secret refers to a provider that is not registered.
+	ErrUnknownSecretProvider = errors.New("unknown secret provider")
+
+	// ErrSecretNotFound is returned by secret providers, if the requested secret or its field does not exist.
+	ErrSecretNotFound = errors.New("secret not found")
+)
+
+// SecretProvider provides secrets from an external source, e.g. files, environment variables or a vault. Providers
+// are registered using [RegisterSecretProvider] and used by the template function `secret`.
+type SecretProvider interface {
+	// Secret gives the content of the secret with the given path. The interpretation of the path depends on the
+	// provider. If the secret does not exist, an error wrapping [ErrSecretNotFound] should be given.
+	Secret(ctx context.Context, path string) ([]byte, error)
+}
+
+// SecretProviderFunc is a function implementing the [SecretProvider] interface.
+type SecretProviderFunc func(ctx context.Context, path string) ([]byte, error)
+
+// Secret calls the function.
+func (f SecretProviderFunc) Secret(ctx context.Context, path string) ([]byte, error) {
+	return f(ctx, path)
+}
+
+// secretProviders holds the registered secret providers by their name.
+var secretProviders = struct { //nolint:gochecknoglobals
+	sync.RWMutex
+
+	providers map[string]SecretProvider
+}{
+	providers: map[string]SecretProvider{
+		"file": FileSecretProvider{},
+		"env":  EnvSecretProvider{},
+	},
+}
+
+// RegisterSecretProvider registers the secret provider with the given name, replacing a provider of the same name.
+// Registering nil removes the provider. The providers `file` and `env` are registered by default, [ExecSecretProvider]
+// has to be registered explicitly, as it runs external commands.
+func RegisterSecretProvider(name string, provider SecretProvider) {
+	secretProviders.Lock()
+	defer secretProviders.Unlock()
+
+	if provider == nil {
+		delete(secretProviders.providers, name)
+
+		return
+	}
+
+	secretProviders.providers[name] = provider
+}
+
+// secretProvider gives the registered secret provider with the given name.
+func secretProvider(name string) (SecretProvider, bool) {
+	secretProviders.RLock()
+	defer secretProviders.RUnlock()
+
+	provider, found := secretProviders.providers[name]
+
+	return provider, found
+}
+
+// FileSecretProvider provides secrets from files, e.g. Kubernetes or Docker secrets mounted into the container. The
+// paths are relative to the directory Dir, if it is set.
+type FileSecretProvider struct {
+	Dir string
+}
+
+// Secret gives the content of the file with the given path.
+func (p FileSecretProvider) Secret(_ context.Context, path string) ([]byte, error) {
+	return p.read(func(name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Clean(name))
+	}, path)
+}
+
+// file gives the name of the file of the secret with the given path.
+func (p FileSecretProvider) file(path string) string {
+	if len(p.Dir) > 0 {
+		return filepath.Join(p.Dir, path)
+	}
+
+	return path
+}
+
+// read reads the secret with the given path using the given function to open the file.
+func (p FileSecretProvider) read(open func(string) (io.ReadCloser, error), path string) ([]byte, error) {
+	file, openErr := open(p.file(path))
+
+	if errors.Is(openErr, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %w", ErrSecretNotFound, openErr)
+	}
+
+	if openErr != nil {
+		return nil, fmt.Errorf("could not read secret: %w", openErr)
+	}
+
+	defer func() { _ = file.Close() }()
+
+	content, readErr := io.ReadAll(file)
+
+	return content, wrapError("could not read secret: %w", readErr)
+}
+
+// EnvSecretProvider provides secrets from environment variables. The path is the name of the variable.
+type EnvSecretProvider struct{}
+
+// Secret gives the value of the environment variable with the given name.
+func (EnvSecretProvider) Secret(_ context.Context, name string) ([]byte, error) {
+	value, found := os.LookupEnv(name)
+
+	if !found {
+		return nil, fmt.Errorf("%w: environment variable %v", ErrSecretNotFound, name)
+	}
+
+	return []byte(value), nil
+}
+
+// ExecSecretProvider provides secrets from the output of external commands, e.g. of password managers. The path is
+// the command line, split at white space. As it runs arbitrary commands given in the configuration, it is not
+// registered by default.
+type ExecSecretProvider struct{}
+
+// Secret gives the standard output of the given command line.
+func (ExecSecretProvider) Secret(ctx context.Context, commandLine string) ([]byte, error) {
+	args := strings.Fields(commandLine)
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%w: empty command", ErrSecretNotFound)
+	}
+
+	stderr := bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...) //nolint:gosec
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not run %v: %w: %v", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return output, nil
+}
+
+// lookupSecret is a template function giving the secret referred to as `provider:path#field`. Without field, the
+// content of the secret is given without trailing line breaks. With field, the content is parsed as YAML or JSON
+// mapping and the value of the field is given.
+func lookupSecret(reference string) (string, error) {
+	return lookupSecretContext(context.Background(), reference)
+}
+
+// lookupSecretContext gives the secret referred to like lookupSecret, using the given context for the provider.
+func lookupSecretContext(ctx context.Context, reference string) (string, error) {
+	return lookupSecretWith(ctx, reference, func(name, _ string) (SecretProvider, bool) {
+		return secretProvider(name)
+	})
+}
+
+// lookupSecretWith gives the secret referred to like lookupSecret, using the given function to get the provider of a
+// secret by its name and path. Waiting for the provider is aborted, when the given context is done.
+func lookupSecretWith(
+	ctx context.Context,
+	reference string,
+	providerOf func(name, path string) (SecretProvider, bool),
+) (string, error) {
+	name, path, found := strings.Cut(reference, ":")
+
+	if !found {
+		return "", fmt.Errorf("%w: no provider given in %v", ErrUnknownSecretProvider, reference)
+	}
+
+	field := ""
+
+	if index := strings.LastIndex(path, "#"); index >= 0 {
+		path, field = path[:index], path[index+1:]
+	}
+
+	provider, providerFound := providerOf(name, path)
+
+	if !providerFound {
+		return "", fmt.Errorf("%w: %v", ErrUnknownSecretProvider, name)
+	}
+
+	content, err := withContext(ctx, func() ([]byte, error) {
+		return provider.Secret(ctx, path)
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("could not get secret %v: %w", reference, err)
+	}
+
+	if len(field) == 0 {
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	var fields map[string]any
+
+	if err := yaml.Unmarshal(content, &fields); err != nil {
+		return "", fmt.Errorf("could not parse secret %v: %w", reference, err)
+	}
+
+	value, fieldFound := fields[field]
+
+	if !fieldFound {
+		return "", fmt.Errorf("%w: field %v of %v", ErrSecretNotFound, field, reference)
+	}
+
+	return fmt.Sprint(value), nil
+}
@@ -0,0 +1,317 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/AlphaOne1/templig"
+)
+
+type TestProviderConfig struct {
+	User string `yaml:"user"`
+	Pass string `yaml:"pass"`
+}
+
+func loadProviderConfig(t *testing.T, in string) (*TestProviderConfig, error) {
+	t.Helper()
+
+	c, err := templig.From[TestProviderConfig](strings.NewReader(in))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Get(), nil
+}
+
+func TestSecretProviders(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "db-pass"), []byte("filePass\n"), 0o600); err != nil {
+		t.Fatalf("could not write secret: %v", err)
+	}
+
+	err := os.WriteFile(filepath.Join(dir, "db.json"), []byte(`{"user": "jsonUser", "pass": 1234}`), 0o600)
+
+	if err != nil {
+		t.Fatalf("could not write secret: %v", err)
+	}
+
+	t.Setenv("TEST_SECRET_PASS", "envPass")
+
+	tests := []struct {
+		in       string
+		wantUser string
+		wantPass string
+		wantErr  error
+	}{
+		{ // 0
+			in:       `pass: {{ secret "file:` + filepath.Join(dir, "db-pass") + `" }}`,
+			wantPass: "filePass",
+		},
+		{ // 1
+			in: `user: {{ secret "file:` + filepath.Join(dir, "db.json") + `#user" }}
+pass: {{ secret "file:` + filepath.Join(dir, "db.json") + `#pass" | quote }}`,
+			wantUser: "jsonUser",
+			wantPass: "1234",
+		},
+		{ // 2
+			in:       `pass: {{ secret "env:TEST_SECRET_PASS" }}`,
+			wantPass: "envPass",
+		},
+		{ // 3
+			in:      `pass: {{ secret "env:TEST_SECRET_MISSING" }}`,
+			wantErr: templig.ErrSecretNotFound,
+		},
+		{ // 4
+			in:      `pass: {{ secret "file:` + filepath.Join(dir, "db.json") + `#missing" }}`,
+			wantErr: templig.ErrSecretNotFound,
+		},
+		{ // 5
+			in:      `pass: {{ secret "file:` + filepath.Join(dir, "missing") + `" }}`,
+			wantErr: templig.ErrSecretNotFound,
+		},
+		{ // 6
+			in:      `pass: {{ secret "exec:echo execPass" }}`,
+			wantErr: templig.ErrUnknownSecretProvider,
+		},
+		{ // 7
+			in:      `pass: {{ secret "no provider" }}`,
+			wantErr: templig.ErrUnknownSecretProvider,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("%d", k), func(t *testing.T) {
+			got, err := loadProviderConfig(t, test.in)
+
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Errorf("expected %v but got %v", test.wantErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("could not load configuration: %v", err)
+			}
+
+			if got.User != test.wantUser || got.Pass != test.wantPass {
+				t.Errorf("expected %v/%v but got %v/%v", test.wantUser, test.wantPass, got.User, got.Pass)
+			}
+		})
+	}
+}
+
+func TestFileSecretProviderDir(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "pass"), []byte("mounted"), 0o600); err != nil {
+		t.Fatalf("could not write secret: %v", err)
+	}
+
+	templig.RegisterSecretProvider("mount", templig.FileSecretProvider{Dir: dir})
+	t.Cleanup(func() { templig.RegisterSecretProvider("mount", nil) })
+
+	got, err := loadProviderConfig(t, `pass: {{ secret "mount:pass" }}`)
+
+	if err != nil {
+		t.Fatalf("could not load configuration: %v", err)
+	}
+
+	if got.Pass != "mounted" {
+		t.Errorf("expected mounted but got %v", got.Pass)
+	}
+}
+
+func TestExecSecretProvider(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo not available")
+	}
+
+	templig.RegisterSecretProvider("exec", templig.ExecSecretProvider{})
+	t.Cleanup(func() { templig.RegisterSecretProvider("exec", nil) })
+
+	got, err := loadProviderConfig(t, `pass: {{ secret "exec:echo execPass" }}`)
+
+	if err != nil {
+		t.Fatalf("could not load configuration: %v", err)
+	}
+
+	if got.Pass != "execPass" {
+		t.Errorf("expected execPass but got %v", got.Pass)
+	}
+}
+
+func TestCustomSecretProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/db":
+			_, _ = io.WriteString(w, `{"user": "vaultUser", "pass": "vaultPass"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	var gotCtx context.Context
+
+	templig.RegisterSecretProvider("vault",
+		templig.SecretProviderFunc(func(ctx context.Context, path string) ([]byte, error) {
+			gotCtx = ctx
+
+			req, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v1/secret/"+path, nil)
+
+			if reqErr != nil {
+				return nil, reqErr
+			}
+
+			req.Header.Set("X-Vault-Token", "token")
+
+			res, resErr := http.DefaultClient.Do(req)
+
+			if resErr != nil {
+				return nil, resErr
+			}
+
+			defer func() { _ = res.Body.Close() }()
+
+			if res.StatusCode == http.StatusNotFound {
+				return nil, templig.ErrSecretNotFound
+			}
+
+			return io.ReadAll(res.Body)
+		}))
+	t.Cleanup(func() { templig.RegisterSecretProvider("vault", nil) })
+
+	type ctxKey struct{}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "loading")
+
+	c, err := templig.Load[TestProviderConfig](
+		templig.WithContext(ctx),
+		templig.WithReaders(strings.NewReader(`
+user: {{ secret "vault:db#user" }}
+pass: {{ secret "vault:db#pass" }}
+`)))
+
+	if err != nil {
+		t.Fatalf("could not load configuration: %v", err)
+	}
+
+	if c.Get().User != "vaultUser" || c.Get().Pass != "vaultPass" {
+		t.Errorf("expected vaultUser/vaultPass but got %v/%v", c.Get().User, c.Get().Pass)
+	}
+
+	if gotCtx == nil || gotCtx.Value(ctxKey{}) != "loading" {
+		t.Errorf("expected the context of the loading process")
+	}
+
+	if _, err := loadProviderConfig(t, `pass: {{ secret "vault:missing" }}`); !errors.Is(err, templig.ErrSecretNotFound) {
+		t.Errorf("expected %v but got %v", templig.ErrSecretNotFound, err)
+	}
+}
+
+func TestSecretProviderContext(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	templig.RegisterSecretProvider("hung",
+		templig.SecretProviderFunc(func(context.Context, string) ([]byte, error) {
+			<-release
+
+			return []byte("late"), nil
+		}))
+	t.Cleanup(func() { templig.RegisterSecretProvider("hung", nil) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err := templig.Load[TestProviderConfig](
+		templig.WithContext(ctx),
+		templig.WithReaders(strings.NewReader(`pass: {{ secret "hung:pass" }}`)))
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v but got %v", context.DeadlineExceeded, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("loading not aborted when the context was done, took %v", elapsed)
+	}
+}
+
+func TestFileSecretProviderFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.yaml":  {Data: []byte(`pass: {{ secret "file:secrets/pass" }}`)},
+		"secrets/pass": {Data: []byte("fsPass\n")},
+	}
+
+	c, err := templig.Load[TestProviderConfig](templig.WithFS(fsys), templig.WithFiles("config.yaml"))
+
+	if err != nil {
+		t.Fatalf("could not load configuration: %v", err)
+	}
+
+	if c.Get().Pass != "fsPass" {
+		t.Errorf("expected fsPass but got %v", c.Get().Pass)
+	}
+}
+
+func TestFileSecretProviderWatch(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	secretPath := filepath.Join(dir, "pass")
+
+	writeFile(t, secretPath, "pass0")
+	writeFile(t, configPath, `pass: {{ secret "file:`+secretPath+`" }}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, watchErr := templig.WatchLoad[TestProviderConfig](ctx, templig.WithFiles(configPath))
+
+	if watchErr != nil {
+		t.Fatalf("could not watch configuration: %v", watchErr)
+	}
+
+	changes := make(chan string, 10)
+
+	w.OnChange(func(_, newConfig *TestProviderConfig) {
+		changes <- newConfig.Pass
+	})
+
+	// rotation of the mounted secret
+	writeFile(t, secretPath, "pass1")
+
+	select {
+	case pass := <-changes:
+		if pass != "pass1" {
+			t.Errorf("expected pass1 but got %v", pass)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change notification received")
+	}
+}
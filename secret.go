@@ -0,0 +1,177 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted is the text given instead of the value of a [Secret].
+const redacted = "<redacted>"
+
+// Secret holds a secret configuration value of type T. It is decoded transparently from YAML and JSON, but its value
+// is only accessible using [Secret.Reveal]. In all textual representations, i.e. printing using the fmt package,
+// logging using log/slog and encoding to YAML or JSON, the value is redacted. Fields of type Secret are hidden by the
+// ToSecretsHidden family of functions regardless of their key.
+type Secret[T any] struct {
+	value T
+}
+
+// NewSecret creates a new [Secret] holding the given value.
+func NewSecret[T any](value T) Secret[T] {
+	return Secret[T]{value: value}
+}
+
+// Reveal gives the value of the secret.
+func (s Secret[T]) Reveal() T {
+	return s.value
+}
+
+// String gives the redacted representation of the secret.
+func (s Secret[T]) String() string {
+	return redacted
+}
+
+// GoString gives the redacted representation of the secret for the `%#v` verb.
+func (s Secret[T]) GoString() string {
+	return fmt.Sprintf("templig.Secret[%v]{%v}", reflect.TypeFor[T](), redacted)
+}
+
+// Format gives the redacted representation of the secret for all verbs of the fmt package, so that verbs like `%d`
+// cannot reveal the value.
+func (s Secret[T]) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		_, _ = io.WriteString(f, s.GoString())
+
+		return
+	}
+
+	_, _ = io.WriteString(f, redacted)
+}
+
+// LogValue gives the redacted representation of the secret for log/slog.
+func (s Secret[T]) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+// MarshalYAML encodes the secret redacted.
+func (s Secret[T]) MarshalYAML() (any, error) {
+	return redacted, nil
+}
+
+// MarshalJSON encodes the secret redacted.
+func (s Secret[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redacted) //nolint:wrapcheck
+}
+
+// UnmarshalYAML decodes the value of the secret as if it was of type T.
+func (s *Secret[T]) UnmarshalYAML(node *yaml.Node) error {
+	return node.Decode(&s.value) //nolint:wrapcheck
+}
+
+// UnmarshalJSON decodes the value of the secret as if it was of type T.
+func (s *Secret[T]) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &s.value) //nolint:wrapcheck
+}
+
+// secretType gives the type of the value of a Secret.
+func (s Secret[T]) secretType() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+// secretReveal gives the value of the secret, without knowing its type.
+func (s Secret[T]) secretReveal() any {
+	return s.value
+}
+
+// secretValue is implemented by all instances of [Secret].
+type secretValue interface {
+	secretType() reflect.Type
+	secretReveal() any
+}
+
+// secretValueType is the type of the secretValue interface.
+var secretValueType = reflect.TypeFor[secretValue]() //nolint:gochecknoglobals
+
+// isSecretType checks if the given type, after removing pointer indirections, is a [Secret].
+func isSecretType(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.Implements(secretValueType)
+}
+
+// maskSecretValues replaces the nodes of the [Secret] values in the given node structure, representing the given
+// value, with their masked real values. This way, masks are applied to the real values, e.g. to give fingerprints.
+// The runtime value is used, so that secrets in values of interface type are found, too. Real values are only put
+// into the node structure masked.
+func maskSecretValues(node *yaml.Node, v reflect.Value, hideStructure bool, mask Mask) {
+	for v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if node == nil || !v.IsValid() || (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+		return
+	}
+
+	if s, isSecret := v.Interface().(secretValue); isSecret {
+		maskSecretValue(node, s, hideStructure, mask)
+
+		return
+	}
+
+	if v.Kind() == reflect.Pointer {
+		maskSecretValues(node, v.Elem(), hideStructure, mask)
+
+		return
+	}
+
+	switch {
+	case node.Kind == yaml.DocumentNode:
+		for _, c := range node.Content {
+			maskSecretValues(c, v, hideStructure, mask)
+		}
+	case node.Kind == yaml.MappingNode && v.Kind() == reflect.Struct:
+		fields, _ := yamlFields(v.Type())
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if f, found := findField(fields, node.Content[i].Value); found {
+				if fv, fieldErr := v.FieldByIndexErr(f.index); fieldErr == nil {
+					maskSecretValues(node.Content[i+1], fv, hideStructure, mask)
+				}
+			}
+		}
+	case node.Kind == yaml.MappingNode && v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := reflect.ValueOf(node.Content[i].Value).Convert(v.Type().Key())
+			maskSecretValues(node.Content[i+1], v.MapIndex(key), hideStructure, mask)
+		}
+	case node.Kind == yaml.SequenceNode && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array):
+		for i, c := range node.Content {
+			if i < v.Len() {
+				maskSecretValues(c, v.Index(i), hideStructure, mask)
+			}
+		}
+	}
+}
+
+// maskSecretValue replaces the given node by the masked encoded value of the given secret. If the value cannot be
+// encoded or is null, the node is kept as is.
+func maskSecretValue(node *yaml.Node, s secretValue, hideStructure bool, mask Mask) {
+	revealed := yaml.Node{}
+
+	if err := revealed.Encode(s.secretReveal()); err != nil || revealed.ShortTag() == "!!null" {
+		return
+	}
+
+	hideAll(&revealed, hideStructure, mask)
+	*node = revealed
+}
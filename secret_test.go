@@ -0,0 +1,218 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/AlphaOne1/templig"
+)
+
+type TestSecretConfig struct {
+	User     string                   `yaml:"user"`
+	Token    templig.Secret[string]   `yaml:"token"`
+	Pin      templig.Secret[int]      `yaml:"pin"`
+	Backups  templig.Secret[[]string] `yaml:"backups"`
+	Optional *templig.Secret[string]  `yaml:"optional"`
+}
+
+func TestSecretDecode(t *testing.T) {
+	c, loadErr := templig.From[TestSecretConfig](strings.NewReader(`
+user: alice
+token: s3cr3t
+pin: 1234
+backups: [b0, b1]
+optional: opt
+`))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration: %v", loadErr)
+	}
+
+	got := c.Get()
+
+	if got.Token.Reveal() != "s3cr3t" {
+		t.Errorf("expected token s3cr3t but got %v", got.Token.Reveal())
+	}
+
+	if got.Pin.Reveal() != 1234 {
+		t.Errorf("expected pin 1234 but got %v", got.Pin.Reveal())
+	}
+
+	if strings.Join(got.Backups.Reveal(), ",") != "b0,b1" {
+		t.Errorf("expected backups b0,b1 but got %v", got.Backups.Reveal())
+	}
+
+	if got.Optional == nil || got.Optional.Reveal() != "opt" {
+		t.Errorf("expected optional opt but got %v", got.Optional)
+	}
+}
+
+func TestSecretRedacted(t *testing.T) {
+	s := templig.NewSecret(4711)
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "String", got: s.String(), want: "<redacted>"},
+		{name: "%v", got: fmt.Sprintf("%v", s), want: "<redacted>"},
+		{name: "%+v", got: fmt.Sprintf("%+v", struct{ S templig.Secret[int] }{s}), want: "{S:<redacted>}"},
+		{name: "%d", got: fmt.Sprintf("%d", s), want: "<redacted>"},
+		{name: "%#v", got: fmt.Sprintf("%#v", s), want: "templig.Secret[int]{<redacted>}"},
+		{name: "LogValue", got: slog.AnyValue(s).Resolve().String(), want: "<redacted>"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.got != test.want {
+				t.Errorf("expected %v but got %v", test.want, test.got)
+			}
+		})
+	}
+}
+
+func TestSecretMarshal(t *testing.T) {
+	in := TestSecretConfig{
+		User:  "alice",
+		Token: templig.NewSecret("s3cr3t"),
+		Pin:   templig.NewSecret(1234),
+	}
+
+	yamlOut, yamlErr := yaml.Marshal(in)
+
+	if yamlErr != nil {
+		t.Fatalf("could not encode YAML: %v", yamlErr)
+	}
+
+	jsonOut, jsonErr := json.Marshal(in)
+
+	if jsonErr != nil {
+		t.Fatalf("could not encode JSON: %v", jsonErr)
+	}
+
+	for _, out := range []string{string(yamlOut), string(jsonOut)} {
+		if strings.Contains(out, "s3cr3t") || strings.Contains(out, "1234") {
+			t.Errorf("secret revealed in %v", out)
+		}
+
+		if !strings.Contains(out, "redacted") {
+			t.Errorf("expected redacted value in %v", out)
+		}
+	}
+
+	var decoded TestSecretConfig
+
+	if err := json.Unmarshal([]byte(`{"token":"s3cr3t","pin":1234}`), &decoded); err != nil {
+		t.Fatalf("could not decode JSON: %v", err)
+	}
+
+	if decoded.Token.Reveal() != "s3cr3t" || decoded.Pin.Reveal() != 1234 {
+		t.Errorf("expected decoded secrets but got %v and %v", decoded.Token.Reveal(), decoded.Pin.Reveal())
+	}
+}
+
+func TestSecretFromEnvAndHidden(t *testing.T) {
+	t.Setenv("APP_TOKEN", "fromenv")
+	t.Setenv("APP_PIN", "42")
+
+	c, loadErr := templig.Load[TestSecretConfig](
+		templig.WithReaders(strings.NewReader("user: alice\n")),
+		templig.WithEnv("APP"))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration: %v", loadErr)
+	}
+
+	if c.Get().Token.Reveal() != "fromenv" || c.Get().Pin.Reveal() != 42 {
+		t.Errorf("expected secrets from environment but got %v and %v",
+			c.Get().Token.Reveal(), c.Get().Pin.Reveal())
+	}
+
+	buf := bytes.Buffer{}
+
+	if err := c.ToSecretsHidden(&buf); err != nil {
+		t.Fatalf("could not write configuration: %v", err)
+	}
+
+	want := `user: alice
+token: '*******'
+pin: '**'
+backups: '*'
+optional: null
+`
+
+	if buf.String() != want {
+		t.Errorf("expected\n%v\nbut got\n%v", want, buf.String())
+	}
+}
+
+func TestSecretFingerprints(t *testing.T) {
+	c, loadErr := templig.From[TestSecretConfig](strings.NewReader(`
+token: one
+pin: 1234
+optional: two
+`))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration: %v", loadErr)
+	}
+
+	mask := templig.MaskFingerprint("salt")
+	buf := bytes.Buffer{}
+
+	if err := c.ToSecretsHidden(&buf, templig.WithMask(mask)); err != nil {
+		t.Fatalf("could not write configuration: %v", err)
+	}
+
+	want := "user: \"\"\n" +
+		"token: " + mask("one") + "\n" +
+		"pin: " + mask("1234") + "\n" +
+		"backups: '*'\n" +
+		"optional: " + mask("two") + "\n"
+
+	if buf.String() != want {
+		t.Errorf("expected\n%v\nbut got\n%v", want, buf.String())
+	}
+}
+
+func TestSecretInInterfaceHidden(t *testing.T) {
+	type interfaceConfig struct {
+		Extra map[string]any `yaml:"extra"`
+		List  []any          `yaml:"list"`
+	}
+
+	c, loadErr := templig.From[interfaceConfig](strings.NewReader("extra: {}\n"))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration: %v", loadErr)
+	}
+
+	c.Get().Extra = map[string]any{"dsn": templig.NewSecret("hunter2")}
+	c.Get().List = []any{templig.NewSecret("s3cr3t")}
+
+	mask := templig.MaskFingerprint("salt")
+	buf := bytes.Buffer{}
+
+	if err := c.ToSecretsHiddenStructured(&buf, templig.WithMask(mask)); err != nil {
+		t.Fatalf("could not write configuration: %v", err)
+	}
+
+	want := "extra:\n" +
+		"    dsn: " + mask("hunter2") + "\n" +
+		"list:\n" +
+		"    - " + mask("s3cr3t") + "\n"
+
+	if buf.String() != want {
+		t.Errorf("expected\n%v\nbut got\n%v", want, buf.String())
+	}
+}
@@ -4,7 +4,11 @@
 package templig
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -21,33 +25,155 @@ var SecretRE = regexp.MustCompile(SecretDefaultRE)
 // HideSecrets hides secrets in the given YAML node structure. Secrets are identified using the [SecretRE].
 // Depending on the parameter `hideStructure`, the structure of the secret is hidden too (`true`) or visible (`false`).
 func HideSecrets(node *yaml.Node, hideStructure bool) {
+	hideSecrets(node, hideStructure, SecretRE)
+}
+
+// Mask masks the value of a secret, see [WithMask].
+type Mask func(value string) string
+
+// maskText is the text of masks of fixed length.
+const maskText = "********"
+
+// MaskLength replaces the value with the number of * corresponding to its length. It is the default mask, but reveals
+// the length of the secret.
+func MaskLength(value string) string {
+	return strings.Repeat("*", len(value))
+}
+
+// MaskRedacted replaces the value with the placeholder `<redacted>`.
+func MaskRedacted(string) string {
+	return redacted
+}
+
+// MaskFixed gives a mask replacing the value with the given number of *, independent of its length.
+func MaskFixed(length int) Mask {
+	return func(string) string {
+		return strings.Repeat("*", max(length, 0))
+	}
+}
+
+// MaskKeep gives a mask keeping the given number of first and last characters of the value, replacing the rest by
+// a fixed number of *, e.g. `sk-l********ab12`. Values too short to hide at least as many characters as are kept
+// are masked completely.
+func MaskKeep(first, last int) Mask {
+	first, last = max(first, 0), max(last, 0)
+
+	return func(value string) string {
+		runes := []rune(value)
+
+		if len(runes) < 2*(first+last) {
+			return maskText
+		}
+
+		return string(runes[:first]) + maskText + string(runes[len(runes)-last:])
+	}
+}
+
+// MaskFingerprint gives a mask replacing the value with its salted SHA-256 fingerprint, e.g. `sha256:9f86d081884c7d65`.
+// Using the same salt, equal secrets give equal fingerprints, so secrets can be compared across environments without
+// revealing them. The salt should not be published, as short secrets could be guessed otherwise.
+func MaskFingerprint(salt string) Mask {
+	return func(value string) string {
+		sum := sha256.Sum256([]byte(salt + value))
+
+		return "sha256:" + hex.EncodeToString(sum[:fingerprintSize])
+	}
+}
+
+// fingerprintSize is the number of bytes of the SHA-256 sum given by fingerprints.
+const fingerprintSize = 8
+
+// hideSecrets hides secrets in the given YAML node structure. Secrets are identified using the given regular
+// expression.
+func hideSecrets(node *yaml.Node, hideStructure bool, secretRE *regexp.Regexp) {
+	hideTypedSecrets(node, nil, hideStructure, secretRE, MaskLength)
+}
+
+// hideTypedSecrets hides secrets in the given YAML node structure representing a value of the given type. Values of
+// type [Secret] and fields of structures tagged `templig:"secret"` are always hidden, fields tagged
+// `templig:"nosecret"` never, all other values are identified using the given regular expression on their keys.
+// Without type, only the regular expression is used. Scalar values are replaced using the given mask.
+func hideTypedSecrets(node *yaml.Node, t reflect.Type, hideStructure bool, secretRE *regexp.Regexp, mask Mask) {
 	if node == nil {
 		return
 	}
 
-	if node.Kind == yaml.MappingNode {
-		for i := 0; i < len(node.Content); i += 2 {
-			if SecretRE.MatchString(strings.ToLower(node.Content[i].Value)) {
-				hideAll(node.Content[i+1], hideStructure)
+	if t != nil && isSecretType(t) && node.ShortTag() != "!!null" {
+		hideAll(node, hideStructure, mask)
+
+		return
+	}
+
+	if t != nil {
+		t = derefType(t)
+	}
+
+	switch {
+	case node.Kind == yaml.MappingNode:
+		var fields []fieldInfo
+
+		if t != nil && t.Kind() == reflect.Struct {
+			fields, _ = yamlFields(t)
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			childType := mappingChildType(t, fields, key)
+			secret, noSecret := false, false
+
+			if index := slices.IndexFunc(fields, func(f fieldInfo) bool { return f.key == key }); index >= 0 {
+				secret = hasTagOption(fields[index].tag, "secret")
+				noSecret = hasTagOption(fields[index].tag, "nosecret")
+			}
+
+			if secret || (!noSecret && secretRE.MatchString(strings.ToLower(key))) {
+				hideAll(node.Content[i+1], hideStructure, mask)
 			} else {
-				HideSecrets(node.Content[i+1], hideStructure)
+				hideTypedSecrets(node.Content[i+1], childType, hideStructure, secretRE, mask)
 			}
 		}
-	} else {
+	case node.Kind == yaml.SequenceNode && t != nil && isListType(t):
+		for _, v := range node.Content {
+			hideTypedSecrets(v, t.Elem(), hideStructure, secretRE, mask)
+		}
+	case node.Kind == yaml.DocumentNode:
+		for _, v := range node.Content {
+			hideTypedSecrets(v, t, hideStructure, secretRE, mask)
+		}
+	default:
 		for _, v := range node.Content {
-			HideSecrets(v, hideStructure)
+			hideTypedSecrets(v, nil, hideStructure, secretRE, mask)
 		}
 	}
 }
 
-func hideAll(node *yaml.Node, hideStructure bool) {
+// mappingChildType gives the type of the value of the given key of a mapping representing the type t with the
+// given fields. If it is unknown, nil is given.
+func mappingChildType(t reflect.Type, fields []fieldInfo, key string) reflect.Type {
+	switch {
+	case t == nil:
+		return nil
+	case t.Kind() == reflect.Map:
+		return t.Elem()
+	case t.Kind() == reflect.Struct:
+		if index := slices.IndexFunc(fields, func(f fieldInfo) bool { return f.key == key }); index >= 0 {
+			return fields[index].typ
+		}
+	}
+
+	return nil
+}
+
+// hideAll hides all values of the given node structure. Scalars are replaced using the given mask, structures are
+// replaced by a single '*', if their structure is to be hidden.
+func hideAll(node *yaml.Node, hideStructure bool, mask Mask) {
 	switch node.Kind {
 	case yaml.ScalarNode:
 		node.Tag = "!!str"
-		node.Value = strings.Repeat("*", len(node.Value))
+		node.Value = mask(node.Value)
 	case yaml.AliasNode:
 		if node.Alias != nil {
-			hideAll(node.Alias, hideStructure)
+			hideAll(node.Alias, hideStructure, mask)
 		}
 	default:
 		if hideStructure {
@@ -57,7 +183,7 @@ func hideAll(node *yaml.Node, hideStructure bool) {
 			node.Content = nil
 		} else {
 			for _, v := range node.Content {
-				hideAll(v, hideStructure)
+				hideAll(v, hideStructure, mask)
 			}
 		}
 	}
@@ -5,6 +5,9 @@ package templig_test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -200,3 +203,151 @@ pass: *ref
 		t.Errorf("unexpected output:\n%v\nwanted:\n%v", buf.String(), want)
 	}
 }
+
+type TestTaggedSecretsDB struct {
+	DSN      string   `yaml:"dsn"      templig:"secret"`
+	Password string   `yaml:"password"`
+	Replicas []string `yaml:"replicas" templig:"secret"`
+}
+
+type TestTaggedSecretsConfig struct {
+	KeyboardLayout string                         `yaml:"keyboard_layout" templig:"nosecret"`
+	APIKey         string                         `yaml:"api_key"`
+	Databases      []TestTaggedSecretsDB          `yaml:"databases"`
+	Named          map[string]TestTaggedSecretsDB `yaml:"named"`
+	Public         TestTaggedSecretsPublic        `yaml:"certificates" templig:"nosecret"`
+}
+
+type TestTaggedSecretsPublic struct {
+	Authority string `yaml:"authority"`
+	Phrase    string `yaml:"passphrase"`
+}
+
+func TestTaggedSecretsHidden(t *testing.T) {
+	c, loadErr := templig.From[TestTaggedSecretsConfig](strings.NewReader(`
+keyboard_layout: de
+api_key: abc
+databases:
+  - dsn: postgres://user:pw@db
+    password: pw
+    replicas: [r0]
+named:
+  main:
+    dsn: postgres://main
+certificates:
+  authority: ca
+  passphrase: tok
+`))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration: %v", loadErr)
+	}
+
+	want := `keyboard_layout: de
+api_key: '***'
+databases:
+    - dsn: '*********************'
+      password: '**'
+      replicas:
+        - '**'
+named:
+    main:
+        dsn: '***************'
+        password: ""
+        replicas: []
+certificates:
+    authority: ca
+    passphrase: '***'
+`
+
+	buf := bytes.Buffer{}
+
+	if err := c.ToSecretsHiddenStructured(&buf); err != nil {
+		t.Fatalf("could not write configuration: %v", err)
+	}
+
+	if buf.String() != want {
+		t.Errorf("expected\n%v\nbut got\n%v", want, buf.String())
+	}
+}
+
+func TestMasks(t *testing.T) {
+	fingerprint := sha256.Sum256([]byte("salt" + "s3cr3t-value"))
+
+	tests := []struct {
+		name string
+		mask templig.Mask
+		in   string
+		want string
+	}{
+		{name: "length", mask: templig.MaskLength, in: "s3cr3t", want: "******"},
+		{name: "redacted", mask: templig.MaskRedacted, in: "s3cr3t", want: "<redacted>"},
+		{name: "fixed", mask: templig.MaskFixed(4), in: "s3cr3t-value", want: "****"},
+		{name: "keep", mask: templig.MaskKeep(3, 2), in: "sk-s3cr3t-value", want: "sk-********ue"},
+		{name: "keep unicode", mask: templig.MaskKeep(1, 1), in: "äöüß", want: "ä********ß"},
+		{name: "keep short", mask: templig.MaskKeep(3, 2), in: "s3cr", want: "********"},
+		{name: "fingerprint", mask: templig.MaskFingerprint("salt"), in: "s3cr3t-value",
+			want: "sha256:" + hex.EncodeToString(fingerprint[:8])},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.mask(test.in); got != test.want {
+				t.Errorf("expected %v but got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestSecretsHiddenWithMask(t *testing.T) {
+	c, loadErr := templig.From[TestTaggedSecretsConfig](strings.NewReader(`
+keyboard_layout: de
+api_key: abc
+databases:
+  - dsn: postgres://user:pw@db
+    password: pw
+    replicas: [r0]
+`))
+
+	if loadErr != nil {
+		t.Fatalf("could not load configuration: %v", loadErr)
+	}
+
+	want := `keyboard_layout: de
+api_key: <redacted>
+databases:
+    - dsn: <redacted>
+      password: <redacted>
+      replicas:
+        - <redacted>
+named: {}
+certificates:
+    authority: ""
+    passphrase: "<redacted>"
+`
+
+	buf := bytes.Buffer{}
+
+	if err := c.ToSecretsHiddenStructured(&buf, templig.WithMask(templig.MaskRedacted)); err != nil {
+		t.Fatalf("could not write configuration: %v", err)
+	}
+
+	if buf.String() != want {
+		t.Errorf("expected\n%v\nbut got\n%v", want, buf.String())
+	}
+
+	first, second := bytes.Buffer{}, bytes.Buffer{}
+
+	if err := c.ToSecretsHidden(&first, templig.WithMask(templig.MaskFingerprint("salt"))); err != nil {
+		t.Fatalf("could not write configuration: %v", err)
+	}
+
+	if err := c.ToSecretsHidden(&second, templig.WithMask(templig.MaskFingerprint("salt"))); err != nil {
+		t.Fatalf("could not write configuration: %v", err)
+	}
+
+	if first.String() != second.String() || strings.Contains(first.String(), "abc") ||
+		!strings.Contains(first.String(), "api_key: sha256:") {
+		t.Errorf("unexpected fingerprints\n%v\n%v", first.String(), second.String())
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnknownSequenceStrategy indicates that a sequence strategy is not one of the supported ones.
+var ErrUnknownSequenceStrategy = errors.New("unknown sequence strategy")
+
+// SequenceStrategy is a strategy to merge a sequence of an overlay with the corresponding sequence of the underlying
+// configuration. The strategy of a single sequence can also be given in the overlay using the strategy as YAML tag,
+// e.g.
+//
+//	origins: !replace
+//	  - https://prod.example.com
+//
+// The strategy of a sequence is determined in the following order: the tag of the sequence in the overlay, the
+// strategy of its path given by [WithPathSequenceStrategy], the strategy given by [WithSequenceStrategy]. By default,
+// sequences are appended.
+type SequenceStrategy string
+
+const (
+	// SequenceAppend appends the items of the overlay to the items of the underlying sequence.
+	SequenceAppend SequenceStrategy = "append"
+	// SequenceReplace replaces the underlying sequence by the sequence of the overlay.
+	SequenceReplace SequenceStrategy = "replace"
+	// SequencePrepend puts the items of the overlay in front of the items of the underlying sequence.
+	SequencePrepend SequenceStrategy = "prepend"
+	// SequenceUnion appends the items of the overlay to the items of the underlying sequence, removing duplicates.
+	SequenceUnion SequenceStrategy = "union"
+
+	// sequenceMergePrefix is the prefix of the strategies merging by key.
+	sequenceMergePrefix = "merge:"
+
+	// sequenceIndex merges the items of the overlay into the items of the underlying sequence at the same index, null
+	// items keep the underlying ones. It is used for list elements given by their index, e.g. `MYAPP_HOSTS__1`.
+	sequenceIndex SequenceStrategy = "index"
+)
+
+// SequenceMergeByKey gives the strategy that merges items of the overlay into the items of the underlying sequence
+// having the same value for the given key, e.g. `name`. Items without a match are appended. As YAML tag, it is given
+// as `!merge:name`.
+func SequenceMergeByKey(key string) SequenceStrategy {
+	return SequenceStrategy(sequenceMergePrefix + key)
+}
+
+// mergeKey gives the key of strategies merging by key.
+func (s SequenceStrategy) mergeKey() (string, bool) {
+	key, found := strings.CutPrefix(string(s), sequenceMergePrefix)
+
+	return key, found && len(key) > 0
+}
+
+// valid checks if the strategy is one of the supported ones.
+func (s SequenceStrategy) valid() bool {
+	switch s {
+	case SequenceAppend, SequenceReplace, SequencePrepend, SequenceUnion:
+		return true
+	default:
+		_, isMerge := s.mergeKey()
+
+		return isMerge
+	}
+}
+
+// WithSequenceStrategy sets the strategy used to merge sequences of overlays, see [SequenceStrategy].
+func WithSequenceStrategy(strategy SequenceStrategy) Option {
+	return func(o *options) {
+		o.sequences = strategy
+	}
+}
+
+// WithPathSequenceStrategy sets the strategy used to merge the sequence at the given dotted path, e.g.
+// `cors.origins`, see [SequenceStrategy].
+func WithPathSequenceStrategy(path string, strategy SequenceStrategy) Option {
+	return func(o *options) {
+		if o.pathSequences == nil {
+			o.pathSequences = make(map[string]SequenceStrategy)
+		}
+
+		o.pathSequences[path] = strategy
+	}
+}
+
+// sequenceStrategyTag gives the strategy given by the tag of the node, if any.
+func sequenceStrategyTag(node *yaml.Node) (SequenceStrategy, bool) {
+	if node.Kind != yaml.SequenceNode || !strings.HasPrefix(node.Tag, "!") || strings.HasPrefix(node.Tag, "!!") {
+		return "", false
+	}
+
+	strategy := SequenceStrategy(node.Tag[1:])
+
+	return strategy, strategy.valid()
+}
+
+// takeSequenceTags records the strategies given as tags of the sequences of the given node structure and replaces the
+// tags by the standard sequence tag, so that the structure can be decoded. The internal strategy merging by index is
+// rejected, it is only given by the layers of environment variables and command line flags, see takeIndexTags.
+func (m *merger) takeSequenceTags(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode && node.Tag == "!"+string(sequenceIndex) {
+		return fmt.Errorf("%w: %v in %v", ErrUnknownSequenceStrategy, sequenceIndex, m.position(node))
+	}
+
+	if strategy, found := sequenceStrategyTag(node); found {
+		m.setNodeStrategy(node, strategy)
+	}
+
+	var errs []error
+
+	for _, c := range node.Content {
+		errs = append(errs, m.takeSequenceTags(c))
+	}
+
+	return errors.Join(errs...)
+}
+
+// takeIndexTags records the sequences of the given node structure tagged to be merged by index, as created for list
+// elements given by their index, and replaces their tags by the standard sequence tag.
+func (m *merger) takeIndexTags(node *yaml.Node) {
+	if node.Kind == yaml.SequenceNode && node.Tag == "!"+string(sequenceIndex) {
+		m.setNodeStrategy(node, sequenceIndex)
+	}
+
+	for _, c := range node.Content {
+		m.takeIndexTags(c)
+	}
+}
+
+// setNodeStrategy records the strategy of the given sequence node and replaces its tag by the standard sequence tag.
+func (m *merger) setNodeStrategy(node *yaml.Node, strategy SequenceStrategy) {
+	if m.nodeStrategies == nil {
+		m.nodeStrategies = make(map[*yaml.Node]SequenceStrategy)
+	}
+
+	m.nodeStrategies[node] = strategy
+	node.Tag = "!!seq"
+}
+
+// sequenceStrategy gives the strategy to merge the sequence `b` into the sequence `a` at the current path.
+func (m *merger) sequenceStrategy(a, b *yaml.Node) SequenceStrategy {
+	if strategy, found := m.nodeStrategies[b]; found {
+		return strategy
+	}
+
+	if strategy, found := sequenceStrategyTag(b); found {
+		return strategy
+	}
+
+	if strategy, found := m.pathStrategies[strings.Join(m.path, ".")]; found {
+		return strategy
+	}
+
+	if o, found := m.origins[a]; found && m.weakLayers[o.layer] {
+		return SequenceReplace
+	}
+
+	if len(m.strategy) > 0 {
+		return m.strategy
+	}
+
+	return SequenceAppend
+}
+
+// pushPath enters the given element of the current path.
+func (m *merger) pushPath(element string) {
+	m.path = append(m.path, element)
+}
+
+// popPath leaves the last element of the current path.
+func (m *merger) popPath() {
+	m.path = m.path[:len(m.path)-1]
+}
+
+// mergeSequenceItems merges the items of the sequence `b` into the copy `ret` of the sequence `a` using the given
+// strategy.
+func (m *merger) mergeSequenceItems(ret, a, b *yaml.Node, strategy SequenceStrategy) error {
+	ret.Content = make([]*yaml.Node, 0, len(a.Content)+len(b.Content))
+
+	switch strategy {
+	case SequenceAppend:
+		ret.Content = append(ret.Content, a.Content...)
+		ret.Content = append(ret.Content, b.Content...)
+	case SequencePrepend:
+		ret.Content = append(ret.Content, b.Content...)
+		ret.Content = append(ret.Content, a.Content...)
+	case SequenceUnion:
+		for _, c := range append(append([]*yaml.Node{}, a.Content...), b.Content...) {
+			if !containsEqualNode(ret.Content, c) {
+				ret.Content = append(ret.Content, c)
+			}
+		}
+	case SequenceReplace:
+		ret.Content = append(ret.Content, b.Content...)
+	case sequenceIndex:
+		ret.Content = append(ret.Content, a.Content...)
+
+		if err := m.mergeIndexedItems(ret, b); err != nil {
+			return err
+		}
+	default:
+		key, isMerge := strategy.mergeKey()
+
+		if !isMerge {
+			return fmt.Errorf("%w: %v", ErrUnknownSequenceStrategy, strategy)
+		}
+
+		ret.Content = append(ret.Content, a.Content...)
+
+		for _, item := range b.Content {
+			if err := m.mergeSequenceItem(ret, item, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// mergeSequenceItem merges the given item into the item of the sequence having the same value for the given key. If
+// there is no such item, it is appended.
+func (m *merger) mergeSequenceItem(seq *yaml.Node, item *yaml.Node, key string) error {
+	if value := mappingValue(item, key); value != nil {
+		for i, c := range seq.Content {
+			if other := mappingValue(c, key); other == nil || other.Value != value.Value {
+				continue
+			}
+
+			m.pushPath(strconv.Itoa(i))
+			merged, mergeErr := m.merge(c, item)
+			m.popPath()
+
+			if mergeErr != nil {
+				return mergeErr
+			}
+
+			seq.Content[i] = merged
+
+			return nil
+		}
+	}
+
+	seq.Content = append(seq.Content, item)
+
+	return nil
+}
+
+// mergeIndexedItems merges the items of the sequence `b` into the items of the sequence at the same index. Null items
+// of `b` keep the items of the sequence, items beyond its end are appended.
+func (m *merger) mergeIndexedItems(seq *yaml.Node, b *yaml.Node) error {
+	for i, item := range b.Content {
+		switch {
+		case i >= len(seq.Content):
+			seq.Content = append(seq.Content, item)
+		case item.ShortTag() != "!!null":
+			m.pushPath(strconv.Itoa(i))
+			merged, mergeErr := m.merge(seq.Content[i], item)
+			m.popPath()
+
+			if mergeErr != nil {
+				return mergeErr
+			}
+
+			seq.Content[i] = merged
+		}
+	}
+
+	return nil
+}
+
+// mappingValue gives the scalar value of the given key in the mapping node, or nil if there is none.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key && node.Content[i+1].Kind == yaml.ScalarNode {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// containsEqualNode checks if one of the given nodes is equal to the node.
+func containsEqualNode(nodes []*yaml.Node, node *yaml.Node) bool {
+	for _, n := range nodes {
+		if equalNodes(n, node) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// equalNodes checks if the given node structures represent the same value.
+func equalNodes(a, b *yaml.Node) bool {
+	for a.Kind == yaml.AliasNode && a.Alias != nil {
+		a = a.Alias
+	}
+
+	for b.Kind == yaml.AliasNode && b.Alias != nil {
+		b = b.Alias
+	}
+
+	if a.Kind != b.Kind || a.Value != b.Value || len(a.Content) != len(b.Content) ||
+		(a.Kind == yaml.ScalarNode && a.ShortTag() != b.ShortTag()) {
+		return false
+	}
+
+	for i := range a.Content {
+		if !equalNodes(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,166 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AlphaOne1/templig"
+)
+
+type TestSequencesUpstream struct {
+	Name   string `yaml:"name"`
+	URL    string `yaml:"url"`
+	Weight int    `yaml:"weight"`
+}
+
+type TestSequencesConfig struct {
+	Origins   []string                `yaml:"origins"`
+	Upstreams []TestSequencesUpstream `yaml:"upstreams"`
+}
+
+func TestSequenceStrategies(t *testing.T) {
+	base := `
+origins:
+  - a.com
+  - b.com
+upstreams:
+  - name: first
+    url: http://first
+    weight: 1
+  - name: second
+    url: http://second
+`
+
+	tests := []struct {
+		name     string
+		overlay  string
+		opts     []templig.Option
+		expected TestSequencesConfig
+	}{
+		{
+			name:    "default append",
+			overlay: "origins:\n  - b.com\n  - c.com\n",
+			expected: TestSequencesConfig{
+				Origins: []string{"a.com", "b.com", "b.com", "c.com"},
+			},
+		},
+		{
+			name:    "global replace",
+			overlay: "origins:\n  - c.com\n",
+			opts:    []templig.Option{templig.WithSequenceStrategy(templig.SequenceReplace)},
+			expected: TestSequencesConfig{
+				Origins: []string{"c.com"},
+			},
+		},
+		{
+			name:    "global prepend",
+			overlay: "origins:\n  - c.com\n",
+			opts:    []templig.Option{templig.WithSequenceStrategy(templig.SequencePrepend)},
+			expected: TestSequencesConfig{
+				Origins: []string{"c.com", "a.com", "b.com"},
+			},
+		},
+		{
+			name:    "path union",
+			overlay: "origins:\n  - b.com\n  - c.com\n  - c.com\n",
+			opts:    []templig.Option{templig.WithPathSequenceStrategy("origins", templig.SequenceUnion)},
+			expected: TestSequencesConfig{
+				Origins: []string{"a.com", "b.com", "c.com"},
+			},
+		},
+		{
+			name:    "tag replace",
+			overlay: "origins: !replace\n  - c.com\n",
+			opts:    []templig.Option{templig.WithSequenceStrategy(templig.SequencePrepend)},
+			expected: TestSequencesConfig{
+				Origins: []string{"c.com"},
+			},
+		},
+		{
+			name:    "tag merge by key",
+			overlay: "upstreams: !merge:name\n  - name: second\n    weight: 2\n  - name: third\n    url: http://third\n",
+			expected: TestSequencesConfig{
+				Upstreams: []TestSequencesUpstream{
+					{Name: "first", URL: "http://first", Weight: 1},
+					{Name: "second", URL: "http://second", Weight: 2},
+					{Name: "third", URL: "http://third"},
+				},
+			},
+		},
+		{
+			name:    "path merge by key",
+			overlay: "upstreams:\n  - name: first\n    url: http://other\n",
+			opts: []templig.Option{
+				templig.WithPathSequenceStrategy("upstreams", templig.SequenceMergeByKey("name")),
+			},
+			expected: TestSequencesConfig{
+				Upstreams: []TestSequencesUpstream{
+					{Name: "first", URL: "http://other", Weight: 1},
+					{Name: "second", URL: "http://second"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := append([]templig.Option{
+				templig.WithReaders(strings.NewReader(base), strings.NewReader(test.overlay)),
+			}, test.opts...)
+
+			c, loadErr := templig.Load[TestSequencesConfig](opts...)
+
+			if loadErr != nil {
+				t.Fatalf("could not load configuration: %v", loadErr)
+			}
+
+			got := c.Get()
+
+			if test.expected.Origins != nil && !reflect.DeepEqual(got.Origins, test.expected.Origins) {
+				t.Errorf("expected origins %v but got %v", test.expected.Origins, got.Origins)
+			}
+
+			if test.expected.Upstreams != nil && !reflect.DeepEqual(got.Upstreams, test.expected.Upstreams) {
+				t.Errorf("expected upstreams %+v but got %+v", test.expected.Upstreams, got.Upstreams)
+			}
+		})
+	}
+}
+
+func TestSequenceStrategyTagInBase(t *testing.T) {
+	c, loadErr := templig.From[TestSequencesConfig](strings.NewReader("origins: !union\n  - a.com\n"))
+
+	if loadErr != nil {
+		t.Fatalf("strategy tags of the base must be removed for decoding: %v", loadErr)
+	}
+
+	if !reflect.DeepEqual(c.Get().Origins, []string{"a.com"}) {
+		t.Errorf("unexpected origins: %v", c.Get().Origins)
+	}
+}
+
+func TestSequenceStrategyUnknown(t *testing.T) {
+	_, loadErr := templig.Load[TestSequencesConfig](
+		templig.WithReaders(strings.NewReader("origins: [a.com]"), strings.NewReader("origins: [b.com]")),
+		templig.WithSequenceStrategy("shuffle"),
+	)
+
+	if !errors.Is(loadErr, templig.ErrUnknownSequenceStrategy) {
+		t.Errorf("expected error %v but got %v", templig.ErrUnknownSequenceStrategy, loadErr)
+	}
+}
+
+func TestSequenceStrategyIndexTag(t *testing.T) {
+	_, loadErr := templig.Load[TestSequencesConfig](
+		templig.WithReaders(strings.NewReader("origins: [a.com]"), strings.NewReader("origins: !index [b.com]")),
+	)
+
+	if !errors.Is(loadErr, templig.ErrUnknownSequenceStrategy) {
+		t.Errorf("expected error %v but got %v", templig.ErrUnknownSequenceStrategy, loadErr)
+	}
+}
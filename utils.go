@@ -1,6 +1,9 @@
 package templig
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // wrapError returns a formatted error with the given text if an error is provided, otherwise it returns nil.
 func wrapError(text string, err error) error {
@@ -14,3 +17,43 @@ func wrapError(text string, err error) error {
 
 	return nil
 }
+
+// joinPath appends the given key to the dotted path.
+func joinPath(path string, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// withContext runs the given function and waits for its result, as long as the given context is not done. This allows
+// to abort waiting for operations that cannot be canceled themselves, e.g. reading from a hung network mount.
+func withContext[R any](ctx context.Context, f func() (R, error)) (R, error) {
+	if err := context.Cause(ctx); err != nil {
+		var zero R
+
+		return zero, err
+	}
+
+	type result struct {
+		value R
+		err   error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		value, err := f()
+		done <- result{value: value, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		var zero R
+
+		return zero, context.Cause(ctx)
+	case r := <-done:
+		return r.value, r.err
+	}
+}
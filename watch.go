@@ -0,0 +1,359 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultPollInterval is the interval to check for file changes, if no native file change notification is available.
+const defaultPollInterval = 2 * time.Second
+
+// settleTime is the time waited after a change notification for further notifications, so that a series of changes,
+// e.g. by an editor, only triggers one reload.
+const settleTime = 50 * time.Millisecond
+
+// Watcher holds a configuration, that is reloaded whenever one of its files changes. The configuration is only
+// replaced, if the new one could be loaded and validated successfully.
+type Watcher[T any] struct {
+	current  atomic.Pointer[Config[T]]
+	opts     []Option
+	notifier notifier
+
+	mu        sync.Mutex
+	onChange  []func(oldConfig, newConfig *T)
+	onError   []func(error)
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// notifier signals changes of a set of files.
+type notifier interface {
+	// watch sets the files to watch and the patterns of files, whose creation is watched for.
+	watch(paths []string, globs []dependencyGlob) error
+	// changes gives the channel that receives a value on changes of the watched files.
+	changes() <-chan struct{}
+	// close stops the watching.
+	close() error
+}
+
+// Watch loads the configuration from the given files like [FromFile] and reloads it, whenever one of the files or a
+// file read using the `read` template function changes. The watching stops, when the given context is done.
+func Watch[T any](ctx context.Context, paths ...string) (*Watcher[T], error) {
+	if len(paths) == 0 {
+		return nil, ErrNoConfigPaths
+	}
+
+	return WatchLoad[T](ctx, WithFiles(paths...))
+}
+
+// WatchLoad loads the configuration like [Load] and reloads it using the same options, whenever one of the files it
+// depends on changes or a file is added to one of its directories or globs, see [WithDir] and [WithGlob]. The watching
+// stops, when the given context is done.
+func WatchLoad[T any](ctx context.Context, opts ...Option) (*Watcher[T], error) {
+	config, loadErr := Load[T](opts...)
+
+	if loadErr != nil {
+		return nil, loadErr
+	}
+
+	w := &Watcher[T]{
+		opts:     opts,
+		notifier: newNotifier(newOptions(opts...).poll),
+		done:     make(chan struct{}),
+	}
+
+	w.current.Store(config)
+
+	if err := w.notifier.watch(config.dependencies, config.globs); err != nil {
+		_ = w.notifier.close()
+
+		return nil, err
+	}
+
+	go w.run(ctx)
+
+	return w, nil
+}
+
+// Get gives a pointer to the current deserialized configuration. Reloaded configurations are held in new instances,
+// so the content of the returned pointer is not changed by reloading.
+func (w *Watcher[T]) Get() *T {
+	return w.current.Load().Get()
+}
+
+// Config gives the current configuration.
+func (w *Watcher[T]) Config() *Config[T] {
+	return w.current.Load()
+}
+
+// OnChange registers a function that is called with the old and new configuration content after every successful
+// reload.
+func (w *Watcher[T]) OnChange(f func(oldConfig, newConfig *T)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.onChange = append(w.onChange, f)
+}
+
+// OnError registers a function that is called, if a reload failed. In this case, the current configuration is kept.
+func (w *Watcher[T]) OnError(f func(error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.onError = append(w.onError, f)
+}
+
+// Done gives a channel that is closed, when the watching has stopped.
+func (w *Watcher[T]) Done() <-chan struct{} {
+	return w.done
+}
+
+// run waits for file changes and reloads the configuration until the context is done.
+func (w *Watcher[T]) run(ctx context.Context) {
+	defer w.closeOnce.Do(func() {
+		_ = w.notifier.close()
+		close(w.done)
+	})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-w.notifier.changes():
+			if !ok {
+				return
+			}
+		}
+
+		// wait for the changes to settle
+		settled := false
+
+		for !settled {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.notifier.changes():
+			case <-time.After(settleTime):
+				settled = true
+			}
+		}
+
+		w.reload()
+	}
+}
+
+// reload loads the configuration again and replaces the current one, if it was loaded successfully.
+func (w *Watcher[T]) reload() {
+	config, loadErr := Load[T](w.opts...)
+
+	w.mu.Lock()
+	onChange := append([]func(oldConfig, newConfig *T){}, w.onChange...)
+	onError := append([]func(error){}, w.onError...)
+	w.mu.Unlock()
+
+	if loadErr != nil {
+		for _, f := range onError {
+			f(loadErr)
+		}
+
+		return
+	}
+
+	if err := w.notifier.watch(config.dependencies, config.globs); err != nil {
+		for _, f := range onError {
+			f(err)
+		}
+	}
+
+	old := w.current.Swap(config)
+
+	for _, f := range onChange {
+		f(old.Get(), config.Get())
+	}
+}
+
+// fileState is the state of a file used to detect changes by polling.
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// pollNotifier detects file changes by regularly checking their modification time and size. New files matching the
+// watched patterns are detected by expanding the patterns on every check.
+type pollNotifier struct {
+	mu      sync.Mutex
+	states  map[string]fileState
+	globs   []dependencyGlob
+	signal  chan struct{}
+	stop    chan struct{}
+	stopped sync.Once
+}
+
+// newPollNotifier creates a notifier checking the watched files in the given interval.
+func newPollNotifier(interval time.Duration) *pollNotifier {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	p := &pollNotifier{
+		states: make(map[string]fileState),
+		signal: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				if p.check() {
+					notify(p.signal)
+				}
+			}
+		}
+	}()
+
+	return p
+}
+
+// statFile gives the current state of the given file.
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{
+		exists:  true,
+		size:    info.Size(),
+		modTime: info.ModTime(),
+	}
+}
+
+// check compares the current state of the watched files with the last known one.
+func (p *pollNotifier) check() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	changed := false
+
+	for path, state := range p.states {
+		if current := statFile(path); current != state {
+			p.states[path] = current
+			changed = true
+		}
+	}
+
+	for _, path := range globFiles(p.globs) {
+		if _, found := p.states[path]; !found {
+			p.states[path] = statFile(path)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func (p *pollNotifier) watch(paths []string, globs []dependencyGlob) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	absGlobs, globsErr := absoluteGlobs(globs)
+
+	if globsErr != nil {
+		return globsErr
+	}
+
+	states := make(map[string]fileState, len(paths))
+
+	for _, path := range paths {
+		abs, absErr := filepath.Abs(path)
+
+		if absErr != nil {
+			return wrapError("could not watch "+path+": %w", absErr)
+		}
+
+		states[abs] = statFile(abs)
+	}
+
+	// the files currently matching the patterns are known, so that only new files are reported
+	for _, path := range globFiles(absGlobs) {
+		states[path] = statFile(path)
+	}
+
+	for path := range states {
+		if state, found := p.states[path]; found {
+			states[path] = state
+		}
+	}
+
+	p.states = states
+	p.globs = absGlobs
+
+	return nil
+}
+
+// absoluteGlobs gives the given patterns with absolute paths.
+func absoluteGlobs(globs []dependencyGlob) ([]dependencyGlob, error) {
+	result := make([]dependencyGlob, 0, len(globs))
+
+	for _, g := range globs {
+		abs, absErr := filepath.Abs(g.pattern)
+
+		if absErr != nil {
+			return nil, wrapError("could not watch "+g.pattern+": %w", absErr)
+		}
+
+		result = append(result, dependencyGlob{pattern: abs, configOnly: g.configOnly})
+	}
+
+	return result, nil
+}
+
+// globFiles gives the files matching the given patterns.
+func globFiles(globs []dependencyGlob) []string {
+	var result []string
+
+	for _, g := range globs {
+		matches, _ := filepath.Glob(g.pattern)
+
+		for _, m := range matches {
+			if g.matches(m) {
+				result = append(result, m)
+			}
+		}
+	}
+
+	return result
+}
+
+func (p *pollNotifier) changes() <-chan struct{} {
+	return p.signal
+}
+
+func (p *pollNotifier) close() error {
+	p.stopped.Do(func() { close(p.stop) })
+
+	return nil
+}
+
+// notify signals a change on the given channel without blocking. Pending signals are not repeated.
+func notify(signal chan struct{}) {
+	select {
+	case signal <- struct{}{}:
+	default:
+	}
+}
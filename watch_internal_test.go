@@ -0,0 +1,78 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPollNotifier(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	if err := os.WriteFile(path, []byte("id: 1"), 0o600); err != nil {
+		t.Fatalf("could not write test file: %v", err)
+	}
+
+	n := newPollNotifier(10 * time.Millisecond)
+	defer func() { _ = n.close() }()
+
+	if err := n.watch([]string{path}, nil); err != nil {
+		t.Fatalf("could not watch file: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("id: 22"), 0o600); err != nil {
+		t.Fatalf("could not write test file: %v", err)
+	}
+
+	select {
+	case <-n.changes():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change detected")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("could not remove test file: %v", err)
+	}
+
+	select {
+	case <-n.changes():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no removal detected")
+	}
+}
+
+func TestPollNotifierGlob(t *testing.T) {
+	dir := t.TempDir()
+
+	n := newPollNotifier(10 * time.Millisecond)
+	defer func() { _ = n.close() }()
+
+	if err := n.watch(nil, []dependencyGlob{{pattern: filepath.Join(dir, "*"), configOnly: true}}); err != nil {
+		t.Fatalf("could not watch pattern: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "20-name.yaml~"), []byte("name: backup"), 0o600); err != nil {
+		t.Fatalf("could not write test file: %v", err)
+	}
+
+	select {
+	case <-n.changes():
+		t.Fatalf("change detected for ignored file")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "20-name.yaml"), []byte("name: fragment"), 0o600); err != nil {
+		t.Fatalf("could not write test file: %v", err)
+	}
+
+	select {
+	case <-n.changes():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no creation detected")
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build linux
+
+package templig
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// inotifyMask are the events of a directory that indicate a change of a contained file.
+const inotifyMask = syscall.IN_CLOSE_WRITE |
+	syscall.IN_MODIFY |
+	syscall.IN_CREATE |
+	syscall.IN_DELETE |
+	syscall.IN_MOVED_TO |
+	syscall.IN_MOVED_FROM |
+	syscall.IN_ATTRIB
+
+// inotifyNotifier detects file changes using the Linux inotify facility. It watches the directories containing the
+// files, so that files replaced by renaming, as many editors do, are still recognized. For symlinks, the directories
+// containing their targets are watched as well.
+type inotifyNotifier struct {
+	fd     int
+	file   *os.File
+	mu     sync.Mutex
+	dirs   map[string]int
+	wds    map[int]string
+	files  map[string]bool
+	links  []string
+	globs  []dependencyGlob
+	signal chan struct{}
+}
+
+// newNotifier creates the notifier for the current platform, falling back to polling with the given interval, if
+// inotify is not available.
+func newNotifier(pollInterval time.Duration) notifier {
+	if n, err := newInotifyNotifier(); err == nil {
+		return n
+	}
+
+	return newPollNotifier(pollInterval)
+}
+
+// newInotifyNotifier creates a new inotify based notifier.
+func newInotifyNotifier() (*inotifyNotifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+
+	if err != nil {
+		return nil, wrapError("could not initialize inotify: %w", err)
+	}
+
+	n := &inotifyNotifier{
+		fd:     fd,
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		dirs:   make(map[string]int),
+		wds:    make(map[int]string),
+		files:  make(map[string]bool),
+		signal: make(chan struct{}, 1),
+	}
+
+	go n.read()
+
+	return n, nil
+}
+
+// read processes the inotify events until the notifier is closed.
+func (n *inotifyNotifier) read() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+
+	for {
+		count, err := n.file.Read(buf)
+
+		if err != nil {
+			if !errors.Is(err, os.ErrDeadlineExceeded) && !errors.Is(err, syscall.EINTR) {
+				return
+			}
+
+			continue
+		}
+
+		if n.relevant(buf[:count]) {
+			notify(n.signal)
+		}
+	}
+}
+
+// relevant checks if the given inotify events concern one of the watched files.
+func (n *inotifyNotifier) relevant(events []byte) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	result := false
+
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(events); {
+		event := (*syscall.InotifyEvent)(unsafe.Pointer(&events[offset])) //nolint:gosec
+		nameStart := offset + syscall.SizeofInotifyEvent
+		nameEnd := nameStart + int(event.Len)
+
+		if nameEnd > len(events) {
+			break
+		}
+
+		name := string(bytes.TrimRight(events[nameStart:nameEnd], "\x00"))
+
+		if dir, found := n.wds[int(event.Wd)]; found && n.watched(filepath.Join(dir, name)) {
+			result = true
+		}
+
+		// symlinks may lead to the watched files, e.g. the `..data` link swapped by Kubernetes for mounted ConfigMaps
+		if dir, found := n.wds[int(event.Wd)]; found && len(name) > 0 && n.linked(filepath.Join(dir, name)) {
+			result = true
+		}
+
+		offset = nameEnd
+	}
+
+	return result
+}
+
+// linked checks if the file with the given name is a symlink, that resolves to one of the watched files or is part of
+// the path of the target of one of their symlinks, like the `..data` link in `..data/config.yaml`.
+func (n *inotifyNotifier) linked(name string) bool {
+	if info, err := os.Lstat(name); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return false
+	}
+
+	if resolved, err := filepath.EvalSymlinks(name); err == nil && n.files[resolved] {
+		return true
+	}
+
+	return slices.ContainsFunc(n.links, func(target string) bool {
+		return target == name || strings.HasPrefix(target, name+string(filepath.Separator))
+	})
+}
+
+// linkTargets gives the targets of the chain of symlinks starting at the file with the given name, without resolving
+// symlinks in their directories.
+func linkTargets(name string) []string {
+	var result []string
+
+	for range maxLinks {
+		target, err := os.Readlink(name)
+
+		if err != nil {
+			break
+		}
+
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(name), target)
+		}
+
+		name = filepath.Clean(target)
+		result = append(result, name)
+	}
+
+	return result
+}
+
+// maxLinks is the maximum length of the chains of symlinks followed by linkTargets.
+const maxLinks = 40
+
+// watched checks if the file with the given name is one of the watched files or matches one of the watched patterns.
+func (n *inotifyNotifier) watched(name string) bool {
+	return n.files[name] || slices.ContainsFunc(n.globs, func(g dependencyGlob) bool { return g.matches(name) })
+}
+
+func (n *inotifyNotifier) watch(paths []string, globs []dependencyGlob) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	absGlobs, globsErr := absoluteGlobs(globs)
+
+	if globsErr != nil {
+		return globsErr
+	}
+
+	files := make(map[string]bool, len(paths))
+	dirs := make(map[string]bool)
+	var links []string
+
+	for _, g := range absGlobs {
+		// only existing directories can be watched, the directories of the pattern may contain wildcards as well
+		matches, _ := filepath.Glob(filepath.Dir(g.pattern))
+
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.IsDir() {
+				dirs[m] = true
+			}
+		}
+	}
+
+	for _, path := range paths {
+		abs, absErr := filepath.Abs(path)
+
+		if absErr != nil {
+			return wrapError("could not watch "+path+": %w", absErr)
+		}
+
+		files[abs] = true
+		dirs[filepath.Dir(abs)] = true
+
+		// changes of the target of a symlink happen in the directory of the target
+		if resolved, resolveErr := filepath.EvalSymlinks(abs); resolveErr == nil && resolved != abs {
+			files[resolved] = true
+			dirs[filepath.Dir(resolved)] = true
+			links = append(links, linkTargets(abs)...)
+		}
+	}
+
+	for dir := range dirs {
+		if _, found := n.dirs[dir]; found {
+			continue
+		}
+
+		wd, err := syscall.InotifyAddWatch(n.fd, dir, inotifyMask)
+
+		if err != nil {
+			return wrapError("could not watch directory "+dir+": %w", err)
+		}
+
+		n.dirs[dir] = wd
+		n.wds[wd] = dir
+	}
+
+	for dir, wd := range n.dirs {
+		if !dirs[dir] {
+			_, _ = syscall.InotifyRmWatch(n.fd, uint32(wd)) //nolint:gosec
+			delete(n.dirs, dir)
+			delete(n.wds, wd)
+		}
+	}
+
+	n.files = files
+	n.links = links
+	n.globs = absGlobs
+
+	return nil
+}
+
+func (n *inotifyNotifier) changes() <-chan struct{} {
+	return n.signal
+}
+
+func (n *inotifyNotifier) close() error {
+	return wrapError("could not close inotify: %w", n.file.Close())
+}
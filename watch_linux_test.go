@@ -0,0 +1,71 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInotifyNotifierSymlinks(t *testing.T) {
+	// layout of Kubernetes ConfigMap mounts: config.yaml -> ..data/config.yaml, ..data -> ..v1
+	dir := t.TempDir()
+
+	for _, d := range []string{"..v1", "..v2"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0o700); err != nil {
+			t.Fatalf("could not create directory %v: %v", d, err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, d, "config.yaml"), []byte("id: 1"), 0o600); err != nil {
+			t.Fatalf("could not write test file: %v", err)
+		}
+	}
+
+	if err := os.Symlink("..v1", filepath.Join(dir, "..data")); err != nil {
+		t.Fatalf("could not create symlink: %v", err)
+	}
+
+	if err := os.Symlink(filepath.Join("..data", "config.yaml"), filepath.Join(dir, "config.yaml")); err != nil {
+		t.Fatalf("could not create symlink: %v", err)
+	}
+
+	n, err := newInotifyNotifier()
+
+	if err != nil {
+		t.Skipf("inotify not available: %v", err)
+	}
+
+	defer func() { _ = n.close() }()
+
+	if err := n.watch([]string{filepath.Join(dir, "config.yaml")}, nil); err != nil {
+		t.Fatalf("could not watch file: %v", err)
+	}
+
+	// unrelated symlinks in the directory are no changes
+	if err := os.Symlink("..v2", filepath.Join(dir, "other")); err != nil {
+		t.Fatalf("could not create symlink: %v", err)
+	}
+
+	select {
+	case <-n.changes():
+		t.Fatalf("change detected for unrelated symlink")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := os.Symlink("..v2", filepath.Join(dir, "..data_tmp")); err != nil {
+		t.Fatalf("could not create symlink: %v", err)
+	}
+
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatalf("could not swap symlink: %v", err)
+	}
+
+	select {
+	case <-n.changes():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change detected for swapped symlink")
+	}
+}
@@ -0,0 +1,14 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !linux
+
+package templig
+
+import "time"
+
+// newNotifier creates the notifier for the current platform. Without native support, polling with the given interval
+// is used.
+func newNotifier(pollInterval time.Duration) notifier {
+	return newPollNotifier(pollInterval)
+}
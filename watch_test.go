@@ -0,0 +1,163 @@
+// Copyright the templig contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package templig_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/templig"
+)
+
+// writeFile writes a test file and fails the test on errors.
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("could not write %v: %v", path, err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	secretPath := filepath.Join(dir, "secret.txt")
+
+	writeFile(t, secretPath, "Name0")
+	writeFile(t, configPath, "id: 9\nname: {{ read \""+secretPath+"\" | quote }}\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w, watchErr := templig.Watch[TestConfigValidated](ctx, configPath)
+
+	if watchErr != nil {
+		t.Fatalf("could not watch configuration: %v", watchErr)
+	}
+
+	changes := make(chan [2]TestConfigValidated, 10)
+	errs := make(chan error, 10)
+
+	w.OnChange(func(oldConfig, newConfig *TestConfigValidated) {
+		changes <- [2]TestConfigValidated{*oldConfig, *newConfig}
+	})
+	w.OnError(func(err error) {
+		errs <- err
+	})
+
+	if w.Get().Name != "Name0" {
+		t.Errorf("expected initial name Name0 but got %v", w.Get().Name)
+	}
+
+	// change in a file read by the template
+	writeFile(t, secretPath, "Name1")
+
+	select {
+	case change := <-changes:
+		if change[0].Name != "Name0" || change[1].Name != "Name1" {
+			t.Errorf("expected change from Name0 to Name1 but got %v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change notification received")
+	}
+
+	// invalid configuration must not replace the current one
+	writeFile(t, configPath, "id: 8\nname: Invalid\n")
+
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no error notification received")
+	}
+
+	if w.Get().ID != 9 || w.Get().Name != "Name1" {
+		t.Errorf("invalid configuration replaced the current one: %+v", w.Get())
+	}
+
+	cancel()
+
+	select {
+	case <-w.Done():
+	case <-time.After(5 * time.Second):
+		t.Errorf("watcher did not stop after context cancellation")
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	if _, err := templig.Watch[TestConfig](context.Background()); err == nil {
+		t.Errorf("expected error watching without files")
+	}
+
+	if _, err := templig.Watch[TestConfig](context.Background(), "testData/test_does_not_exist.yaml"); err == nil {
+		t.Errorf("expected error watching nonexistent file")
+	}
+}
+
+func TestWatchSymlink(t *testing.T) {
+	// layout of Kubernetes ConfigMap mounts: config.yaml -> ..data/config.yaml, ..data -> ..v1
+	dir := t.TempDir()
+
+	for _, d := range []string{"..v1", "..v2"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0o700); err != nil {
+			t.Fatalf("could not create directory %v: %v", d, err)
+		}
+	}
+
+	writeFile(t, filepath.Join(dir, "..v1", "config.yaml"), "id: 1\n")
+
+	if err := os.Symlink("..v1", filepath.Join(dir, "..data")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	if err := os.Symlink(filepath.Join("..data", "config.yaml"), filepath.Join(dir, "config.yaml")); err != nil {
+		t.Fatalf("could not create symlink: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, watchErr := templig.Watch[TestConfig](ctx, filepath.Join(dir, "config.yaml"))
+
+	if watchErr != nil {
+		t.Fatalf("could not watch configuration: %v", watchErr)
+	}
+
+	changes := make(chan int, 10)
+
+	w.OnChange(func(_, newConfig *TestConfig) {
+		changes <- newConfig.ID
+	})
+
+	expectID := func(want int) {
+		t.Helper()
+
+		select {
+		case id := <-changes:
+			if id != want {
+				t.Errorf("expected id %v but got %v", want, id)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no change notification received")
+		}
+	}
+
+	// change of the symlink target
+	writeFile(t, filepath.Join(dir, "..v1", "config.yaml"), "id: 2\n")
+	expectID(2)
+
+	// atomic swap of the data directory
+	writeFile(t, filepath.Join(dir, "..v2", "config.yaml"), "id: 3\n")
+
+	if err := os.Symlink("..v2", filepath.Join(dir, "..data_tmp")); err != nil {
+		t.Fatalf("could not create symlink: %v", err)
+	}
+
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatalf("could not swap symlink: %v", err)
+	}
+
+	expectID(3)
+}